@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// CellChange はドライラン時に検出したセルの変更内容を表す構造体です
+type CellChange struct {
+	Spreadsheet string `json:"spreadsheet,omitempty"` // スプレッドシートID
+	Sheet       string `json:"sheet"`
+	User        string `json:"user"`
+	Date        string `json:"date"`
+	Range       string `json:"range"`
+	Old         string `json:"old"`
+	New         string `json:"new"`
+	ManualEdit  bool   `json:"manualEdit,omitempty"` // 手動で編集されたセル（--forceなしでは書き込まれない）
+}
+
+// ChangeReport はドライランで検出した変更を集計する構造体です
+type ChangeReport struct {
+	mu      sync.Mutex
+	changes []CellChange
+}
+
+// Add は変更を追加します
+func (r *ChangeReport) Add(c CellChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, c)
+}
+
+// Changes はシート・ユーザー・日付順に並べた変更一覧を返します
+func (r *ChangeReport) Changes() []CellChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := append([]CellChange(nil), r.changes...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Spreadsheet != changes[j].Spreadsheet {
+			return changes[i].Spreadsheet < changes[j].Spreadsheet
+		}
+		if changes[i].Sheet != changes[j].Sheet {
+			return changes[i].Sheet < changes[j].Sheet
+		}
+		if changes[i].User != changes[j].User {
+			return changes[i].User < changes[j].User
+		}
+		return changes[i].Date < changes[j].Date
+	})
+	return changes
+}
+
+// HasChanges は実際に書き込まれる変更があるかどうかを返します
+// 手動で編集されたためスキップされるセルは含みません
+func (r *ChangeReport) HasChanges() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.changes {
+		if !c.ManualEdit {
+			return true
+		}
+	}
+	return false
+}
+
+// Print は変更一覧を指定された形式（"table" または "json"）で出力します
+func (r *ChangeReport) Print(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		return r.printTable(w)
+	case "json":
+		return r.printJSON(w)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+func (r *ChangeReport) printTable(w io.Writer) error {
+	changes := r.Changes()
+
+	// 複数のスプレッドシートに書き込む場合はスプレッドシートIDの列を追加する
+	spreadsheets := make(map[string]bool)
+	for _, c := range changes {
+		spreadsheets[c.Spreadsheet] = true
+	}
+	prefix := func(c CellChange) string { return "" }
+	if len(spreadsheets) > 1 {
+		prefix = func(c CellChange) string { return c.Spreadsheet + "\t" }
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, prefix(CellChange{Spreadsheet: "SPREADSHEET"})+"SHEET\tUSER\tDATE\tCELL\tOLD\t→\tNEW\tNOTE")
+	skipped := 0
+	for _, c := range changes {
+		note := ""
+		if c.ManualEdit {
+			note = "manual edit (skipped)"
+			skipped++
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\t→\t%s\t%s\n", prefix(c), c.Sheet, c.User, c.Date, c.Range, c.Old, c.New, note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d cell(s) would change, %d manually edited cell(s) would be skipped\n", len(changes)-skipped, skipped)
+	return err
+}
+
+func (r *ChangeReport) printJSON(w io.Writer) error {
+	changes := r.Changes()
+	if changes == nil {
+		changes = []CellChange{}
+	}
+
+	prettyJSON, err := json.MarshalIndent(struct {
+		Changes []CellChange `json:"changes"`
+	}{changes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(prettyJSON))
+	return err
+}
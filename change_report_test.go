@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestChangeReport(t *testing.T) {
+	report := &ChangeReport{}
+	if report.HasChanges() {
+		t.Fatal("expected no changes in empty report")
+	}
+
+	report.Add(CellChange{Sheet: "R7年度_5月", User: "伊藤", Date: "2025-05-02", Range: "R7年度_5月!J9", Old: "渋谷", New: "週休"})
+	report.Add(CellChange{Sheet: "R7年度_5月", User: "伊藤", Date: "2025-05-01", Range: "R7年度_5月!J8", Old: "", New: "渋谷"})
+	report.Add(CellChange{Sheet: "R7年度_4月", User: "三浦", Date: "2025-04-30", Range: "R7年度_4月!K37", Old: "渋谷", New: "外出"})
+
+	if !report.HasChanges() {
+		t.Fatal("expected changes")
+	}
+
+	changes := report.Changes()
+	expectedOrder := []string{"R7年度_4月!K37", "R7年度_5月!J8", "R7年度_5月!J9"}
+	for i, rng := range expectedOrder {
+		if changes[i].Range != rng {
+			t.Errorf("change %d: expected %s but got %s", i, rng, changes[i].Range)
+		}
+	}
+
+	var table bytes.Buffer
+	if err := report.Print(&table, "table"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(table.String(), "3 cell(s) would change, 0 manually edited") {
+		t.Errorf("unexpected table output:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := report.Print(&out, "json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded struct {
+		Changes []CellChange `json:"changes"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if len(decoded.Changes) != 3 || decoded.Changes[1].New != "渋谷" {
+		t.Errorf("unexpected JSON output: %+v", decoded.Changes)
+	}
+
+	if err := report.Print(&out, "xml"); err == nil {
+		t.Error("expected error for unknown format but got none")
+	}
+}
+
+func TestChangeReportManualEditOnly(t *testing.T) {
+	report := &ChangeReport{}
+	report.Add(CellChange{Sheet: "R7年度_5月", User: "伊藤", Date: "2025-05-01", Range: "R7年度_5月!J8", Old: "在宅", New: "渋谷", ManualEdit: true})
+
+	if report.HasChanges() {
+		t.Error("manually edited cells should not count as changes")
+	}
+}
+
+func TestChangeReportMultipleSpreadsheets(t *testing.T) {
+	report := &ChangeReport{}
+	report.Add(CellChange{Spreadsheet: "book-b", Sheet: "R7年度_4月", User: "伊藤", Date: "2025-04-01", Range: "R7年度_4月!C8", New: "週休"})
+	report.Add(CellChange{Spreadsheet: "book-a", Sheet: "R7年度_4月", User: "三浦", Date: "2025-04-01", Range: "R7年度_4月!C8", New: "外出"})
+
+	if changes := report.Changes(); changes[0].Spreadsheet != "book-a" {
+		t.Errorf("expected book-a first but got %+v", changes)
+	}
+
+	var table bytes.Buffer
+	if err := report.Print(&table, "table"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(table.String(), "\n")
+	if !strings.HasPrefix(lines[0], "SPREADSHEET") || !strings.HasPrefix(lines[1], "book-a") {
+		t.Errorf("expected spreadsheet column in table output:\n%s", table.String())
+	}
+}
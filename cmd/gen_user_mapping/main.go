@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"github.com/eotel/garoon2gs/organizations"
+	"github.com/eotel/garoon2gs/users"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	orgFlag := flag.String("org", "", "メンバーを取得する組織IDのカンマ区切りリスト（必須）")
+	recursive := flag.Bool("recursive", false, "子組織のメンバーも含める")
+	output := flag.String("o", "", "出力するCSVファイルのパス（相対パスは設定ディレクトリから）。省略時はUSER_MAPPING_PATH（未指定の場合はuser_mapping.csv）")
+	prune := flag.Bool("prune", false, "組織にいない既存のユーザーをCSVから削除する")
+	dryRun := flag.Bool("dry-run", false, "CSVを書き込まず、追加・削除されるユーザーのみを出力する")
+	key := flag.String("key", "", "ユーザーを特定する列（user_id, code, email）。省略時は既存のCSVと同じ列（CSVがない場合はuser_id）")
+	flag.Parse()
+
+	orgIDs := splitList(*orgFlag)
+	if len(orgIDs) == 0 {
+		log.Fatal("-org で組織IDを指定してください")
+	}
+
+	// 設定の読み込みとクライアントの初期化
+	config, err := client.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	garoonClient, err := client.NewClient(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 出力先（相対パスは同期と同じファイルになるよう設定ディレクトリからのパス）
+	csvPath := *output
+	if csvPath == "" {
+		csvPath = os.Getenv("USER_MAPPING_PATH")
+		if csvPath == "" {
+			csvPath = "user_mapping.csv"
+		}
+		csvPath = filepath.Join(config.ConfigDir, csvPath)
+	} else if !filepath.IsAbs(csvPath) {
+		csvPath = filepath.Join(config.ConfigDir, csvPath)
+	}
+
+	// Ctrl+C / SIGTERM で実行中のリクエストを中断する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 組織のメンバーを取得
+	userList, err := organizations.GetMembers(
+		ctx,
+		garoonClient.GetHTTPClient(),
+		garoonClient.GetBaseURL(),
+		garoonClient.GetUsername(),
+		garoonClient.GetPassword(),
+		orgIDs,
+		*recursive,
+	)
+	if err != nil {
+		log.Fatalf("組織メンバーの取得に失敗しました: %v", err)
+	}
+
+	members := make([]mapping.UserMapping, 0, len(userList))
+	for _, u := range userList {
+		members = append(members, mapping.UserMapping{UserID: u.ID, Code: u.Code, Email: u.Email, HeaderName: strings.TrimSpace(u.Name)})
+	}
+
+	// 既存のCSVがある場合はヘッダー名などの設定を維持して統合する
+	var existing []mapping.UserMapping
+	existingKey := mapping.KeyUserID
+	if _, err := os.Stat(csvPath); err == nil {
+		existing, existingKey, err = mapping.LoadUserMappingFile(csvPath)
+		if err != nil {
+			log.Fatal("既存のユーザーマッピングの読み込みに失敗しました:", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Fatal("既存のユーザーマッピングの確認に失敗しました:", err)
+	}
+
+	// キーの列を指定しない場合は、既存のCSVと同じ列で出力する
+	if *key == "" {
+		*key = existingKey
+	}
+
+	// キーが code・email の既存のユーザーマッピングは、ユーザー一覧からユーザーIDを求めてから統合する
+	// （code・email で出力する場合は、既存のユーザーの code・email もユーザー一覧から補完する）
+	if mapping.NeedsUserIDs(existing) || (*key != mapping.KeyUserID && len(existing) > 0) {
+		allUsers, err := users.ListUsers(
+			ctx,
+			garoonClient.GetHTTPClient(),
+			garoonClient.GetBaseURL(),
+			garoonClient.GetUsername(),
+			garoonClient.GetPassword(),
+		)
+		if err != nil {
+			log.Fatal("ユーザー一覧の取得に失敗しました:", err)
+		}
+		if existing, err = mapping.ResolveUserIDs(existing, allUsers); err != nil {
+			log.Fatal("既存のユーザーマッピングの読み込みに失敗しました:", err)
+		}
+	}
+
+	merged, added, removed := mapping.MergeUserMapping(existing, members, *prune)
+	report(added, removed, *prune)
+
+	if *dryRun {
+		log.Println("ドライランのため、CSVは書き込みません")
+		return
+	}
+
+	if err := writeFile(csvPath, merged, *key); err != nil {
+		log.Fatal("ユーザーマッピングの書き込みに失敗しました:", err)
+	}
+	log.Printf("%d人のユーザーマッピングを %s に書き込みました", len(merged), csvPath)
+}
+
+// report は追加・削除されるユーザーを出力します
+func report(added, removed []mapping.UserMapping, prune bool) {
+	for _, m := range added {
+		fmt.Printf("+ %s,%s\n", m.UserID, m.HeaderName)
+	}
+	for _, m := range removed {
+		if prune {
+			fmt.Printf("- %s,%s\n", m.UserID, m.HeaderName)
+		} else {
+			fmt.Printf("? %s,%s（組織にいません。削除する場合は -prune を指定してください）\n", m.UserID, m.HeaderName)
+		}
+	}
+	log.Printf("追加: %d人、組織にいないユーザー: %d人", len(added), len(removed))
+}
+
+// writeFile はユーザーマッピングをCSVファイルに書き込みます
+// 書き込み途中で中断されても壊れないよう、一時ファイルに書いてから置き換えます
+func writeFile(csvPath string, mappings []mapping.UserMapping, key string) error {
+	var buf bytes.Buffer
+	if err := mapping.WriteUserMapping(&buf, mappings, key); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(csvPath), ".user_mapping-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %v", csvPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", csvPath, err)
+	}
+	if err := os.Rename(tmp.Name(), csvPath); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", csvPath, err)
+	}
+	return nil
+}
+
+// splitList はカンマ区切りの文字列を空要素を除いたスライスに変換します
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
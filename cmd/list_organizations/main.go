@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/eotel/garoon2gs/internal/client"
 	"github.com/eotel/garoon2gs/organizations"
 	"github.com/eotel/garoon2gs/users"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	var orgID string
+	var filter organizations.Filter
 	flag.StringVar(&orgID, "org", "", "Organization ID to list users for")
+	flag.StringVar(&filter.Name, "name", "", "Search organizations by name")
+	flag.StringVar(&filter.Code, "code", "", "List only the organization with this code")
 	flag.Parse()
 
 	// 設定の読み込みとクライアントの初期化
@@ -24,9 +31,14 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Ctrl+C / SIGTERM で実行中のリクエストを中断する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 組織IDが指定された場合は組織メンバーを表示
 	if orgID != "" {
 		userList, err := organizations.GetOrganizationUsers(
+			ctx,
 			garoonClient.GetHTTPClient(),
 			garoonClient.GetBaseURL(),
 			garoonClient.GetUsername(),
@@ -43,11 +55,13 @@ func main() {
 	}
 
 	// 組織IDが指定されていない場合は組織一覧を表示
-	orgs, err := organizations.ListOrganizations(
+	orgs, err := organizations.SearchOrganizations(
+		ctx,
 		garoonClient.GetHTTPClient(),
 		garoonClient.GetBaseURL(),
 		garoonClient.GetUsername(),
 		garoonClient.GetPassword(),
+		filter,
 	)
 	if err != nil {
 		log.Fatal("組織一覧の取得に失敗しました:", err)
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/eotel/garoon2gs/internal/client"
 	"github.com/eotel/garoon2gs/users"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
+	var filter users.Filter
+	flag.StringVar(&filter.Name, "name", "", "名前で検索する（表示名やログイン名の一部）")
+	flag.StringVar(&filter.Code, "code", "", "ログイン名が一致するユーザーのみを表示する")
+	flag.Parse()
+
 	// 設定の読み込みとクライアントの初期化
 	config, err := client.LoadConfig()
 	if err != nil {
@@ -18,12 +28,18 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Ctrl+C / SIGTERM で実行中のリクエストを中断する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// ユーザー一覧の取得
-	userList, err := users.ListUsers(
+	userList, err := users.SearchUsers(
+		ctx,
 		garoonClient.GetHTTPClient(),
 		garoonClient.GetBaseURL(),
 		garoonClient.GetUsername(),
 		garoonClient.GetPassword(),
+		filter,
 	)
 	if err != nil {
 		log.Fatal("ユーザー一覧の取得に失敗しました:", err)
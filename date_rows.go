@@ -0,0 +1,129 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sheetsEpoch はスプレッドシートのシリアル値（日数）の基準日です
+var sheetsEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var (
+	// "2025/5/1", "2025-05-01", "2025年5月1日" などの年月日
+	ymdPattern = regexp.MustCompile(`^(\d{4})\s*[/\-.年]\s*(\d{1,2})\s*[/\-.月]\s*(\d{1,2})\s*日?$`)
+	// "5/1", "5月1日" などの月日
+	mdPattern = regexp.MustCompile(`^(\d{1,2})\s*[/\-.月]\s*(\d{1,2})\s*日?$`)
+	// "1", "1日" などの日のみ
+	dPattern = regexp.MustCompile(`^(\d+)\s*日?$`)
+	// "5/1(木)" などの末尾の曜日表記
+	weekdaySuffix = regexp.MustCompile(`\s*[(（][^)）]*[)）]\s*$`)
+)
+
+// parseDateCell はDATE列のセルの値から、指定された月の日を取得します
+// 日の数値、日付のシリアル値、"5/1" や "1日" などの文字列を解釈し、
+// 別の月の日付や日付として解釈できない値（小計行など）の場合は false を返します
+func parseDateCell(value interface{}, month time.Time) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return dayFromNumber(int(v), month)
+	case int:
+		return dayFromNumber(v, month)
+	case string:
+		return dayFromString(v, month)
+	default:
+		return 0, false
+	}
+}
+
+// dayFromNumber は日の数値またはシリアル値から日を取得します
+func dayFromNumber(n int, month time.Time) (int, bool) {
+	if n >= 1 && n <= 31 {
+		return validDay(month.Year(), month.Month(), n)
+	}
+
+	// 31より大きい値は日付のシリアル値とみなす
+	if n > 31 {
+		d := sheetsEpoch.AddDate(0, 0, n)
+		if d.Year() == month.Year() && d.Month() == month.Month() {
+			return d.Day(), true
+		}
+	}
+	return 0, false
+}
+
+// dayFromString は日付を表す文字列から日を取得します
+func dayFromString(s string, month time.Time) (int, bool) {
+	s = strings.TrimSpace(weekdaySuffix.ReplaceAllString(strings.TrimSpace(s), ""))
+	if s == "" {
+		return 0, false
+	}
+
+	if m := ymdPattern.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		if y != month.Year() || time.Month(mo) != month.Month() {
+			return 0, false
+		}
+		return validDay(y, time.Month(mo), d)
+	}
+
+	if m := mdPattern.FindStringSubmatch(s); m != nil {
+		mo, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		if time.Month(mo) != month.Month() {
+			return 0, false
+		}
+		return validDay(month.Year(), month.Month(), d)
+	}
+
+	if m := dPattern.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, false
+		}
+		return dayFromNumber(n, month)
+	}
+
+	return 0, false
+}
+
+// validDay は日がその月に存在する場合に true を返します
+func validDay(year int, month time.Month, day int) (int, bool) {
+	if day < 1 || day > daysIn(year, month) {
+		return 0, false
+	}
+	return day, true
+}
+
+// daysIn は指定された月の日数を返します
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// buildDateRowIndex はDATE列の値から日→行番号の対応表を作成します
+// values は firstRow 行目から始まるDATE列の値です。同じ日が複数ある場合は最初の行を使用します
+func buildDateRowIndex(values [][]interface{}, firstRow int, month time.Time) map[int]int {
+	index := make(map[int]int)
+	for i, row := range values {
+		if len(row) == 0 {
+			continue
+		}
+
+		day, ok := parseDateCell(row[0], month)
+		if !ok {
+			continue
+		}
+
+		rowNum := firstRow + i
+		if existing, dup := index[day]; dup {
+			log.Printf("Duplicate date %d found at row %d (already at row %d), ignoring", day, rowNum, existing)
+			continue
+		}
+		index[day] = rowNum
+	}
+	return index
+}
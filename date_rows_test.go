@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateCell(t *testing.T) {
+	may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		value       interface{}
+		expectedDay int
+		expectedOK  bool
+	}{
+		{"日の数値", float64(15), 15, true},
+		{"日の整数", 3, 3, true},
+		{"日の文字列", "31", 31, true},
+		{"日付き", "1日", 1, true},
+		{"月/日", "5/1", 1, true},
+		{"月/日と曜日", "5/2(金)", 2, true},
+		{"月日", "5月10日", 10, true},
+		{"年月日", "2025/05/20", 20, true},
+		{"年月日（ハイフン）", "2025-05-21", 21, true},
+		{"シリアル値", float64(45778), 1, true}, // 2025-05-01
+		{"シリアル値の文字列", "45808", 31, true},   // 2025-05-31
+		{"別の月の月/日", "6/1", 0, false},
+		{"別の年の年月日", "2024/05/01", 0, false},
+		{"別の月のシリアル値", float64(45809), 0, false}, // 2025-06-01
+		{"存在しない日", "5/32", 0, false},
+		{"小計行", "小計", 0, false},
+		{"空文字", "", 0, false},
+		{"0", float64(0), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day, ok := parseDateCell(tt.value, may)
+			if ok != tt.expectedOK || day != tt.expectedDay {
+				t.Errorf("expected (%d, %v) but got (%d, %v)", tt.expectedDay, tt.expectedOK, day, ok)
+			}
+		})
+	}
+}
+
+func TestBuildDateRowIndex(t *testing.T) {
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local)
+
+	// 8行目から始まるDATE列（空行・小計行・重複を含む）
+	values := [][]interface{}{
+		{"1"},
+		{"2"},
+		{},
+		{"小計"},
+		{"2/3"},
+		{"2"},
+		{float64(45716)}, // 2025-02-28
+		{"合計"},
+	}
+
+	index := buildDateRowIndex(values, 8, feb)
+
+	expected := map[int]int{1: 8, 2: 9, 3: 12, 28: 14}
+	if len(index) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, index)
+	}
+	for day, row := range expected {
+		if index[day] != row {
+			t.Errorf("day %d: expected row %d but got %d", day, row, index[day])
+		}
+	}
+}
+
+func TestGetCellPositionWithDateRows(t *testing.T) {
+	writer := &ScheduleWriter{
+		headerRow: 7,
+		nameCol:   "J",
+		dateRows:  map[int]int{1: 8, 3: 12},
+	}
+
+	row, col, err := writer.getCellPosition(time.Date(2025, 2, 3, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row != 12 || col != "J" {
+		t.Errorf("expected J12 but got %s%d", col, row)
+	}
+
+	if _, _, err := writer.getCellPosition(time.Date(2025, 2, 2, 0, 0, 0, 0, time.Local)); err == nil {
+		t.Error("expected error for date missing from date column but got none")
+	}
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/sheets/v4"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSheetsAPI はテスト用のメモリ上のスプレッドシートです
+type fakeSheetsAPI struct {
+	mu          sync.Mutex
+	books       map[string]*fakeBook // スプレッドシートID → スプレッドシート
+	nextSheetID int64
+
+	reads        int // GetValues / BatchGetValues の呼び出し回数
+	batchUpdates int // BatchUpdateValues の呼び出し回数
+	duplicates   int // DuplicateSheet の呼び出し回数
+	deletes      int // DeleteSheet の呼び出し回数
+}
+
+type fakeBook struct {
+	sheets []*fakeSheet
+}
+
+type fakeSheet struct {
+	id    int64
+	title string
+	cells map[fakeCell]interface{}
+}
+
+// fakeCell はセルの位置（行は1始まり、列は0始まり）です
+type fakeCell struct {
+	row int
+	col int
+}
+
+func newFakeSheetsAPI() *fakeSheetsAPI {
+	return &fakeSheetsAPI{books: make(map[string]*fakeBook)}
+}
+
+// addSheet は空のシートを追加します
+func (f *fakeSheetsAPI) addSheet(spreadsheetID, title string) *fakeSheet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	book := f.books[spreadsheetID]
+	if book == nil {
+		book = &fakeBook{}
+		f.books[spreadsheetID] = book
+	}
+
+	f.nextSheetID++
+	sheet := &fakeSheet{id: f.nextSheetID, title: title, cells: make(map[fakeCell]interface{})}
+	book.sheets = append(book.sheets, sheet)
+	return sheet
+}
+
+// addMonthSheet はヘッダー行とDATE列（A列）を持つ月のシートを追加します
+func (f *fakeSheetsAPI) addMonthSheet(spreadsheetID, title string, headerRow int, month time.Time, names ...string) *fakeSheet {
+	sheet := f.addSheet(spreadsheetID, title)
+
+	sheet.cells[fakeCell{headerRow, 0}] = "DATE"
+	sheet.cells[fakeCell{headerRow, 1}] = "DoW"
+	for i, name := range names {
+		sheet.cells[fakeCell{headerRow, 2 + i}] = name
+	}
+
+	for day := 1; day <= daysIn(month.Year(), month.Month()); day++ {
+		sheet.cells[fakeCell{headerRow + day, 0}] = strconv.Itoa(day)
+	}
+	return sheet
+}
+
+func (f *fakeSheetsAPI) sheet(spreadsheetID, title string) (*fakeSheet, error) {
+	book := f.books[spreadsheetID]
+	if book == nil {
+		return nil, fmt.Errorf("spreadsheet %s not found", spreadsheetID)
+	}
+	for _, s := range book.sheets {
+		if s.title == title {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to parse range: sheet %q not found", title)
+}
+
+// get はA1記法のセルの値を文字列で返します（空のセルは空文字）
+func (f *fakeSheetsAPI) get(spreadsheetID, cell string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r, err := parseFakeRange(fakeHelperRange(cell))
+	if err != nil {
+		panic(err)
+	}
+	sheet, err := f.sheet(spreadsheetID, r.sheet)
+	if err != nil {
+		panic(err)
+	}
+	if v, ok := sheet.cells[fakeCell{r.startRow, r.startCol}]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// fakeHelperRange は get / set に渡された "シート名!J8" のシート名を ' で囲みます
+// テストではシート名に空白や記号が含まれていても囲まずに指定できるようにしています
+func fakeHelperRange(cell string) string {
+	i := strings.LastIndex(cell, "!")
+	if i < 0 || strings.HasPrefix(cell, "'") {
+		return cell
+	}
+	return sheetRange(cell[:i], cell[i+1:])
+}
+
+// set はA1記法のセルに値を設定します
+func (f *fakeSheetsAPI) set(spreadsheetID, cell string, value interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r, err := parseFakeRange(fakeHelperRange(cell))
+	if err != nil {
+		panic(err)
+	}
+	sheet, err := f.sheet(spreadsheetID, r.sheet)
+	if err != nil {
+		panic(err)
+	}
+	sheet.cells[fakeCell{r.startRow, r.startCol}] = value
+}
+
+func (f *fakeSheetsAPI) GetValues(ctx context.Context, spreadsheetID, rng string) ([][]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+
+	return f.read(spreadsheetID, rng)
+}
+
+func (f *fakeSheetsAPI) BatchGetValues(ctx context.Context, spreadsheetID string, ranges []string) ([][][]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+
+	result := make([][][]interface{}, 0, len(ranges))
+	for _, rng := range ranges {
+		values, err := f.read(spreadsheetID, rng)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, values)
+	}
+	return result, nil
+}
+
+func (f *fakeSheetsAPI) BatchUpdateValues(ctx context.Context, spreadsheetID string, data []*sheets.ValueRange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batchUpdates++
+
+	for _, vr := range data {
+		r, err := parseFakeRange(vr.Range)
+		if err != nil {
+			return err
+		}
+		sheet, err := f.sheet(spreadsheetID, r.sheet)
+		if err != nil {
+			return err
+		}
+		for i, row := range vr.Values {
+			for j, v := range row {
+				sheet.cells[fakeCell{r.startRow + i, r.startCol + j}] = v
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeSheetsAPI) ListSheets(ctx context.Context, spreadsheetID string) ([]SheetInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+
+	book := f.books[spreadsheetID]
+	if book == nil {
+		return nil, fmt.Errorf("spreadsheet %s not found", spreadsheetID)
+	}
+
+	infos := make([]SheetInfo, 0, len(book.sheets))
+	for i, s := range book.sheets {
+		infos = append(infos, SheetInfo{ID: s.id, Title: s.title, Index: int64(i)})
+	}
+	return infos, nil
+}
+
+func (f *fakeSheetsAPI) DuplicateSheet(ctx context.Context, spreadsheetID string, sourceSheetID int64, title string, index int64) (SheetInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return SheetInfo{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.duplicates++
+
+	book := f.books[spreadsheetID]
+	if book == nil {
+		return SheetInfo{}, fmt.Errorf("spreadsheet %s not found", spreadsheetID)
+	}
+
+	var source *fakeSheet
+	for _, s := range book.sheets {
+		if s.title == title {
+			return SheetInfo{}, fmt.Errorf("a sheet with the name %q already exists", title)
+		}
+		if s.id == sourceSheetID {
+			source = s
+		}
+	}
+	if source == nil {
+		return SheetInfo{}, fmt.Errorf("sheet %d not found", sourceSheetID)
+	}
+	if index < 0 || index > int64(len(book.sheets)) {
+		return SheetInfo{}, fmt.Errorf("invalid sheet index %d", index)
+	}
+
+	f.nextSheetID++
+	sheet := &fakeSheet{id: f.nextSheetID, title: title, cells: make(map[fakeCell]interface{}, len(source.cells))}
+	for c, v := range source.cells {
+		sheet.cells[c] = v
+	}
+	book.sheets = append(book.sheets[:index], append([]*fakeSheet{sheet}, book.sheets[index:]...)...)
+	return SheetInfo{ID: sheet.id, Title: title, Index: index}, nil
+}
+
+func (f *fakeSheetsAPI) DeleteSheet(ctx context.Context, spreadsheetID string, sheetID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deletes++
+
+	book := f.books[spreadsheetID]
+	if book == nil {
+		return fmt.Errorf("spreadsheet %s not found", spreadsheetID)
+	}
+	for i, s := range book.sheets {
+		if s.id == sheetID {
+			book.sheets = append(book.sheets[:i], book.sheets[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("sheet %d not found", sheetID)
+}
+
+// read は範囲の値を、Sheets APIと同様に末尾の空の行・列を除いて返します
+func (f *fakeSheetsAPI) read(spreadsheetID, rng string) ([][]interface{}, error) {
+	r, err := parseFakeRange(rng)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := f.sheet(spreadsheetID, r.sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	// 範囲の終わりが省略されている場合は値のある最後の行・列まで
+	endRow, endCol := r.endRow, r.endCol
+	for c := range sheet.cells {
+		if r.endRow < 0 && c.row > endRow {
+			endRow = c.row
+		}
+		if r.endCol < 0 && c.col > endCol {
+			endCol = c.col
+		}
+	}
+
+	var values [][]interface{}
+	lastNonEmpty := -1
+	for row := r.startRow; row <= endRow; row++ {
+		var rowValues []interface{}
+		lastCol := -1
+		for col := r.startCol; col <= endCol; col++ {
+			v, ok := sheet.cells[fakeCell{row, col}]
+			if !ok {
+				v = ""
+			} else {
+				lastCol = col - r.startCol
+			}
+			rowValues = append(rowValues, v)
+		}
+		rowValues = rowValues[:lastCol+1]
+		if len(rowValues) > 0 {
+			lastNonEmpty = len(values)
+		}
+		values = append(values, rowValues)
+	}
+	return values[:lastNonEmpty+1], nil
+}
+
+// fakeRange はA1記法の範囲を解析した結果です（終わりが省略された場合は-1）
+type fakeRange struct {
+	sheet    string
+	startRow int
+	startCol int
+	endRow   int
+	endCol   int
+}
+
+var fakeCellPattern = regexp.MustCompile(`^([A-Z]*)(\d*)$`)
+
+// parseFakeRange は "Sheet!J8", "'Sheet 1'!7:7", "Sheet!A8:A" などの範囲を解析します
+// 実際のAPIと同様に、空白や記号を含むシート名は ' で囲まれていなければエラーにします
+func parseFakeRange(rng string) (fakeRange, error) {
+	sheetName, a1, err := parseFakeSheetName(rng)
+	if err != nil {
+		return fakeRange{}, err
+	}
+
+	startStr, endStr, isRange := strings.Cut(a1, ":")
+	startCol, startRow, err := parseFakeCell(startStr)
+	if err != nil {
+		return fakeRange{}, err
+	}
+
+	r := fakeRange{sheet: sheetName, startRow: startRow, startCol: startCol, endRow: startRow, endCol: startCol}
+	if !isRange {
+		return r, nil
+	}
+
+	endCol, endRow, err := parseFakeCell(endStr)
+	if err != nil {
+		return fakeRange{}, err
+	}
+	r.endRow, r.endCol = endRow, endCol
+
+	// 行のみ（"7:7"）の場合はすべての列、列のみ（"A8:A"）の場合は最後の行まで
+	if startCol < 0 {
+		r.startCol = 0
+	}
+	if startRow < 0 {
+		r.startRow = 1
+	}
+	return r, nil
+}
+
+// fakeUnquotedSheetName は ' で囲まずに指定できるシート名です
+var fakeUnquotedSheetName = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// parseFakeSheetName は範囲をシート名と "!" より後のセル範囲に分けます
+func parseFakeSheetName(rng string) (string, string, error) {
+	if !strings.HasPrefix(rng, "'") {
+		sheetName, a1, ok := strings.Cut(rng, "!")
+		if !ok {
+			return "", "", fmt.Errorf("range %q has no sheet name", rng)
+		}
+		if !fakeUnquotedSheetName.MatchString(sheetName) {
+			return "", "", fmt.Errorf("unable to parse range: %s", rng)
+		}
+		return sheetName, a1, nil
+	}
+
+	// 閉じる ' を探す（2つ続く ' はシート名中の ' を表す）
+	for i := 1; i < len(rng); i++ {
+		if rng[i] != '\'' {
+			continue
+		}
+		if i+1 < len(rng) && rng[i+1] == '\'' {
+			i++
+			continue
+		}
+		a1, ok := strings.CutPrefix(rng[i+1:], "!")
+		if !ok {
+			return "", "", fmt.Errorf("unable to parse range: %s", rng)
+		}
+		return strings.ReplaceAll(rng[1:i], "''", "'"), a1, nil
+	}
+	return "", "", fmt.Errorf("unable to parse range: %s", rng)
+}
+
+// parseFakeCell は "J8" を (列, 行) に変換します（省略された部分は-1）
+func parseFakeCell(s string) (int, int, error) {
+	m := fakeCellPattern.FindStringSubmatch(s)
+	if m == nil || s == "" {
+		return 0, 0, fmt.Errorf("invalid cell reference %q", s)
+	}
+
+	col := -1
+	if m[1] != "" {
+		col = 0
+		for _, ch := range m[1] {
+			col = col*26 + int(ch-'A'+1)
+		}
+		col--
+	}
+
+	row := -1
+	if m[2] != "" {
+		row, _ = strconv.Atoi(m[2])
+	}
+	return col, row, nil
+}
+
+func TestFakeSheetsAPI(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book", "R7年度_5月", 7, time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local), "伊藤", "三浦")
+
+	header, err := fake.GetValues(ctx, "book", "R7年度_5月!7:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(header) != 1 || len(header[0]) != 4 || header[0][3] != "三浦" {
+		t.Errorf("unexpected header row: %v", header)
+	}
+
+	dates, err := fake.GetValues(ctx, "book", "R7年度_5月!A8:A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dates) != 31 || dates[30][0] != "31" {
+		t.Errorf("unexpected date column: %v", dates)
+	}
+
+	err = fake.BatchUpdateValues(ctx, "book", []*sheets.ValueRange{
+		{Range: "R7年度_5月!C8", Values: [][]interface{}{{"渋谷"}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values, err := fake.BatchGetValues(ctx, "book", []string{"R7年度_5月!C8", "R7年度_5月!C9"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values[0][0][0] != "渋谷" || len(values[1]) != 0 {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	if _, err := fake.GetValues(ctx, "book", "存在しないシート!A1"); err == nil {
+		t.Error("expected error for unknown sheet but got none")
+	}
+}
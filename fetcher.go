@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"sync"
+	"time"
+)
+
+// defaultConcurrency は予定を同時に取得するユーザー数のデフォルト値です
+const defaultConcurrency = 4
+
+// EventFetcher はユーザーの予定を取得するインターフェースです（*client.GaroonClient が実装します）
+type EventFetcher interface {
+	FetchEvents(ctx context.Context, startDate, endDate time.Time, targetUserID string) ([]client.Event, error)
+}
+
+// FetchResult はユーザーごとの予定の取得結果を表す構造体です
+type FetchResult struct {
+	User   mapping.UserMapping
+	Events []client.Event
+	Err    error
+}
+
+// fetchAll は最大 concurrency 人分の予定を並行して取得します
+// 結果は users と同じ順序で返し、取得に失敗したユーザーは Err に理由を記録します
+// ctx がキャンセルされた場合、未着手のユーザーの Err には ctx.Err() を記録します
+func fetchAll(ctx context.Context, fetcher EventFetcher, users []mapping.UserMapping, startDate, endDate time.Time, concurrency int) []FetchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]FetchResult, len(users))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(users)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				events, err := fetcher.FetchEvents(ctx, startDate, endDate, users[i].UserID)
+				results[i] = FetchResult{User: users[i], Events: events, Err: err}
+			}
+		}()
+	}
+
+	for i, u := range users {
+		if ctx.Err() != nil {
+			results[i] = FetchResult{User: u, Err: ctx.Err()}
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			results[i] = FetchResult{User: u, Err: ctx.Err()}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
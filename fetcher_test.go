@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFetcher はユーザーIDに応じた予定またはエラーを返すテスト用の EventFetcher です
+type fakeFetcher struct {
+	mu      sync.Mutex
+	running int
+	maxSeen int // 同時に実行された FetchEvents の最大数
+	delays  map[string]time.Duration
+	errors  map[string]error
+}
+
+func (f *fakeFetcher) FetchEvents(ctx context.Context, startDate, endDate time.Time, targetUserID string) ([]client.Event, error) {
+	f.mu.Lock()
+	f.running++
+	f.maxSeen = max(f.maxSeen, f.running)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(f.delays[targetUserID]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if err := f.errors[targetUserID]; err != nil {
+		return nil, err
+	}
+	return []client.Event{{ID: "event-" + targetUserID}}, nil
+}
+
+func testUsers(n int) []mapping.UserMapping {
+	users := make([]mapping.UserMapping, 0, n)
+	for i := 1; i <= n; i++ {
+		users = append(users, mapping.UserMapping{UserID: fmt.Sprint(i), HeaderName: fmt.Sprintf("ユーザー%d", i)})
+	}
+	return users
+}
+
+func TestFetchAll(t *testing.T) {
+	users := testUsers(8)
+	fetcher := &fakeFetcher{
+		// 先頭のユーザーほど時間がかかるようにして、完了順と結果の順序が異なることを確認する
+		delays: map[string]time.Duration{"1": 40 * time.Millisecond, "2": 30 * time.Millisecond, "3": 20 * time.Millisecond},
+		errors: map[string]error{"5": fmt.Errorf("APIエラー（ステータスコード: 500）")},
+	}
+
+	results := fetchAll(context.Background(), fetcher, users, time.Time{}, time.Time{}, 3)
+
+	if len(results) != len(users) {
+		t.Fatalf("expected %d results but got %d", len(users), len(results))
+	}
+	for i, r := range results {
+		if r.User.UserID != users[i].UserID {
+			t.Errorf("result %d: expected user %s but got %s", i, users[i].UserID, r.User.UserID)
+		}
+		if r.User.UserID == "5" {
+			if r.Err == nil {
+				t.Errorf("expected error for user 5 but got none")
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("user %s: unexpected error: %v", r.User.UserID, r.Err)
+		}
+		if len(r.Events) != 1 || r.Events[0].ID != "event-"+r.User.UserID {
+			t.Errorf("user %s: unexpected events: %v", r.User.UserID, r.Events)
+		}
+	}
+
+	if fetcher.maxSeen > 3 {
+		t.Errorf("expected at most 3 concurrent fetches but got %d", fetcher.maxSeen)
+	}
+	if fetcher.maxSeen < 2 {
+		t.Errorf("expected fetches to run concurrently but max concurrency was %d", fetcher.maxSeen)
+	}
+}
+
+func TestFetchAllCancelled(t *testing.T) {
+	users := testUsers(5)
+	fetcher := &fakeFetcher{delays: map[string]time.Duration{"1": time.Second, "2": time.Second}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results := fetchAll(ctx, fetcher, users, time.Time{}, time.Time{}, 2)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected fetchAll to stop on context deadline but it took %v", elapsed)
+	}
+
+	for _, r := range results {
+		if r.Err == nil {
+			t.Errorf("user %s: expected error after cancellation but got none", r.User.UserID)
+		}
+	}
+}
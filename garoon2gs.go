@@ -7,12 +7,17 @@ import (
 	"fmt"
 	"github.com/eotel/garoon2gs/internal/client"
 	"github.com/eotel/garoon2gs/internal/mapping"
+	"github.com/eotel/garoon2gs/users"
 	"github.com/joho/godotenv"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -26,8 +31,21 @@ var (
 func main() {
 	// コマンドラインオプションの設定
 	showVersion := flag.Bool("version", false, "バージョン情報を表示")
+	startDateFlag := flag.String("start-date", "", "取得開始日（YYYY-MM-DD）。省略時は今月1日")
+	endDateFlag := flag.String("end-date", "", "取得終了日（YYYY-MM-DD、当日を含む）。省略時は開始月の3ヶ月後の月末")
+	usersFlag := flag.String("users", "", "対象とするユーザーのカンマ区切りリスト（ユーザーID、ログイン名またはメールアドレス）。省略時は全ユーザー")
+	timeout := flag.Duration("timeout", 0, "実行全体の制限時間（例: 10m）。0の場合は無制限")
+	dryRun := flag.Bool("dry-run", false, "書き込みを行わず、変更されるセルの一覧を出力する（変更がある場合は終了コード2）")
+	dryRunFormat := flag.String("dry-run-format", "table", "ドライランの出力形式（table または json）")
+	force := flag.Bool("force", false, "手動で編集されたセルも上書きする")
+	concurrency := flag.Int("concurrency", 0, "予定を同時に取得するユーザー数。省略時はGAROON_CONCURRENCYまたは4")
+	pastDates := flag.String("past-dates", "", "今日より前の日付の扱い（skip, current-month, all, empty-only）。省略時はPAST_DATE_POLICYまたはskip")
 	flag.Parse()
 
+	if *dryRunFormat != "table" && *dryRunFormat != "json" {
+		log.Fatalf("--dry-run-format には table または json を指定してください: %s", *dryRunFormat)
+	}
+
 	// バージョン情報の表示
 	if *showVersion {
 		fmt.Printf("Garoon2GS version %s, commit %s, built at %s\n", version, commit, date)
@@ -60,14 +78,79 @@ func main() {
 		log.Fatal("Garoonクライアントの初期化に失敗しました:", err)
 	}
 
-	// ユーザーマッピングの読み込み
-	userMappings, err := mapping.LoadUserMapping(configDir)
+	// ユーザーの取得元の組織の設定（USER_SOURCE_ORGS）を読み込み
+	source, err := loadUserSource()
+	if err != nil {
+		log.Fatal("ユーザーの取得元の設定が不正です:", err)
+	}
+
+	// ユーザーマッピングの読み込み（組織から取得する場合はヘッダー名などの上書きに使用し、省略可能）
+	var userMappings []mapping.UserMapping
+	if source == nil || os.Getenv("USER_MAPPING_PATH") != "" {
+		userMappings, err = mapping.LoadUserMapping(configDir)
+		if err != nil {
+			log.Fatal("ユーザーマッピングの読み込みに失敗しました:", err)
+		}
+	}
+
+	// Ctrl+C / SIGTERM または制限時間の経過で実行中のリクエストを中断する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	// キーが code・email のユーザーマッピングは、Garoonのユーザー一覧からユーザーIDを求める
+	// --users にログイン名・メールアドレスが指定された場合も、照合のためにユーザー一覧からそれらを求める
+	userKeys := splitList(*usersFlag)
+	if mapping.NeedsUserIDs(userMappings) || !allUserIDs(userKeys) {
+		userList, err := users.ListUsers(ctx,
+			garoonClient.GetHTTPClient(),
+			garoonClient.GetBaseURL(),
+			garoonClient.GetUsername(),
+			garoonClient.GetPassword(),
+		)
+		if err != nil {
+			log.Fatal("ユーザー一覧の取得に失敗しました:", err)
+		}
+		userMappings, err = mapping.ResolveUserIDs(userMappings, userList)
+		if err != nil {
+			log.Fatal("ユーザーマッピングの読み込みに失敗しました:", err)
+		}
+	}
+
+	// 組織のメンバーを実行のたびに取得する
+	if source != nil {
+		userMappings, err = source.Resolve(ctx,
+			garoonClient.GetHTTPClient(),
+			garoonClient.GetBaseURL(),
+			garoonClient.GetUsername(),
+			garoonClient.GetPassword(),
+			userMappings,
+		)
+		if err != nil {
+			log.Fatal("組織からのユーザーの取得に失敗しました:", err)
+		}
+	}
+
+	// 対象ユーザーの絞り込み（シートのヘッダー行には絞り込む前の全員の列を用意する）
+	allUserMappings := userMappings
+	if len(userKeys) > 0 {
+		userMappings, err = mapping.FilterUserMappings(userMappings, userKeys)
+		if err != nil {
+			log.Fatal("対象ユーザーの指定が不正です:", err)
+		}
+	}
+
+	// ユーザーごとの勤務地・勤務曜日・ルールの読み込み
+	profiles, err := loadUserProfiles(configDir, userMappings)
 	if err != nil {
-		log.Fatal("ユーザーマッピングの読み込みに失敗しました:", err)
+		log.Fatal("ユーザーごとの設定の読み込みに失敗しました:", err)
 	}
 
 	// Google Sheets APIクライアントの初期化
-	ctx := context.Background()
 	sheetsService, err := sheets.NewService(ctx,
 		option.WithCredentialsFile(filepath.Join(configDir, os.Getenv("GOOGLE_SERVICE_ACCOUNT_FILE"))),
 		option.WithScopes(sheets.SpreadsheetsScope))
@@ -75,6 +158,13 @@ func main() {
 		log.Fatal("Google Sheetsクライアントの初期化に失敗しました:", err)
 	}
 
+	// 利用上限を超えないよう流量を制限し、429/503の場合は再試行する
+	sheetsQuota, err := LoadSheetsQuota()
+	if err != nil {
+		log.Fatal("Google Sheets APIの利用上限の設定が不正です:", err)
+	}
+	sheetsAPI := NewQuotaSheetsAPI(NewGoogleSheetsAPI(sheetsService), sheetsQuota)
+
 	// 休暇メニューの読み込み
 	holidayMenus, err := loadHolidayMenus()
 	if err != nil {
@@ -82,32 +172,227 @@ func main() {
 	}
 
 	// 期間の設定
-	startDate, endDate := calculateDateRange()
+	startDate, endDate, err := resolveDateRange(*startDateFlag, *endDateFlag)
+	if err != nil {
+		log.Fatal("取得期間の指定が不正です:", err)
+	}
 	log.Printf("取得期間: %s から %s まで", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
-	// 各ユーザーの予定を取得して保存
-	for _, userMapping := range userMappings {
-		log.Printf("ユーザーID %s の予定を取得します", userMapping.UserID)
+	// 過去の日付の扱い（コマンドラインオプションを環境変数より優先）
+	pastDatePolicyStr := *pastDates
+	if pastDatePolicyStr == "" {
+		pastDatePolicyStr = os.Getenv("PAST_DATE_POLICY")
+	}
+	pastDatePolicy, err := ParsePastDatePolicy(pastDatePolicyStr)
+	if err != nil {
+		log.Fatal("過去の日付の扱いの指定が不正です:", err)
+	}
+	if *startDateFlag != "" && pastDatePolicy == PastDateSkip && startDate.Before(startOfDay(time.Now())) {
+		log.Printf("警告: 開始日 %s は今日より前ですが、過去の日付は書き込みません（過去の日付も書き込む場合は --past-dates all を指定してください）", startDate.Format("2006-01-02"))
+	}
+
+	// 予定を同時に取得するユーザー数（コマンドラインオプションを環境変数より優先）
+	fetchConcurrency, err := resolveConcurrency(*concurrency, os.Getenv("GAROON_CONCURRENCY"))
+	if err != nil {
+		log.Fatal("同時実行数の指定が不正です:", err)
+	}
+
+	// 前回書き込んだ値の記録を読み込み（手動編集の検出に使用）
+	statePath := os.Getenv("STATE_PATH")
+	if statePath == "" {
+		statePath = "garoon2gs_state.json"
+	}
+	writeState, err := LoadWriteState(resolveConfigPath(configDir, statePath))
+	if err != nil {
+		log.Fatal("書き込み記録の読み込みに失敗しました:", err)
+	}
+
+	// 書き込みオプションの設定
+	writeOptions := WriteOptions{
+		DryRun:    *dryRun,
+		State:     writeState,
+		Force:     *force,
+		PastDates: pastDatePolicy,
+		Today:     time.Now(),
+		From:      startDate,
+		To:        endDate,
+	}
+	if *dryRun {
+		writeOptions.Report = &ChangeReport{}
+		log.Println("ドライランモード: スプレッドシートへの書き込みは行いません")
+	}
 
-		events, err := garoonClient.FetchEvents(startDate, endDate, userMapping.UserID)
+	// 書き込みの準備（ユーザーと月ごとに書き込み先のスプレッドシートを決定）
+	router, err := NewSpreadsheetRouter(configDir)
+	if err != nil {
+		log.Fatal("書き込み先のスプレッドシートの設定の読み込みに失敗しました:", err)
+	}
+	destinations, err := newSpreadsheetDestinations(router, userMappings, monthsBetween(startDate, endDate), func(spreadsheetID string) (*SheetPlanner, error) {
+		planner, err := NewSheetPlanner(sheetsAPI, spreadsheetID, holidayMenus, writeOptions)
 		if err != nil {
-			log.Printf("警告: ユーザーID %s の予定取得に失敗しました: %v", userMapping.UserID, err)
+			return nil, err
+		}
+		planner.writer.profiles = profiles
+		return planner, nil
+	})
+	if err != nil {
+		log.Fatal("書き込みの準備に失敗しました:", err)
+	}
+
+	for _, spreadsheetID := range destinations.order {
+		planner := destinations.planners[spreadsheetID]
+		if len(destinations.order) > 1 {
+			log.Printf("スプレッドシート %s（%d人）", spreadsheetID, len(destinations.users[spreadsheetID]))
+		}
+
+		// シート一覧から月のシートを探索し、見つからない月と複数見つかった月を報告する
+		missingMonths := destinations.months[spreadsheetID]
+		if planner.DiscoveryEnabled() {
+			discovery, err := planner.DiscoverSheets(ctx, startDate, endDate)
+			if err != nil {
+				log.Fatal("シートの探索に失敗しました:", err)
+			}
+			discovery.Missing = destinations.filterMonths(spreadsheetID, discovery.Missing)
+			logDiscovery(discovery)
+			missingMonths = discovery.Missing
+		}
+
+		// 見つからない月のシートをテンプレートのシートから作成する（探索しない場合は対象期間のすべての月のシートの有無を確認する）
+		headerNames := destinations.headerNames(spreadsheetID, allUserMappings)
+		if planner.CreationEnabled() && len(missingMonths) > 0 {
+			if _, err := planner.CreateSheets(ctx, missingMonths, headerNames); err != nil {
+				log.Fatal("シートの作成に失敗しました:", err)
+			}
+		}
+
+		// 既存のシートのヘッダー行に列がないユーザーの名前を追加する
+		if err := planner.AddMissingNames(ctx, destinations.months[spreadsheetID], headerNames); err != nil {
+			log.Fatal("ヘッダー行への名前の追加に失敗しました:", err)
+		}
+	}
+
+	// 各ユーザーの予定を並行して取得
+	log.Printf("%d人の予定を取得します（同時実行数: %d）", len(userMappings), fetchConcurrency)
+	results := fetchAll(ctx, garoonClient, userMappings, startDate, endDate, fetchConcurrency)
+
+	// 取得に失敗したユーザーはまとめて出力する
+	for _, r := range results {
+		if r.Err != nil && ctx.Err() == nil {
+			log.Printf("警告: ユーザーID %s の予定取得に失敗しました: %v", r.User.UserID, r.Err)
+		}
+	}
+
+	// 取得した予定から、ユーザーマッピングの順にすべてのユーザーの書き込み内容を作成
+	var completed, failed []string
+	var planned []FetchResult
+	for i, result := range results {
+		if ctx.Err() != nil {
+			log.Printf("処理を中断しました: %v", ctx.Err())
+			log.Printf("Google Sheets APIの利用状況: %v", sheetsAPI.Metrics())
+			reportRun(completed, failed, append(usersOf(planned), userMappings[i:]...))
+			os.Exit(1)
+		}
+
+		userMapping, events := result.User, result.Events
+		if result.Err != nil {
+			failed = append(failed, userMapping.UserID)
 			continue
 		}
 
 		if len(events) == 0 {
 			log.Printf("ユーザーID %s の予定は0件でした", userMapping.UserID)
+			completed = append(completed, userMapping.UserID)
 			continue
 		}
 
-		// 予定の書き込み
-		if err := SaveToSheet(sheetsService, os.Getenv("SPREADSHEET_ID"), events, holidayMenus, userMapping.HeaderName); err != nil {
+		if err := destinations.Add(ctx, userMapping, events); err != nil {
 			log.Printf("警告: ユーザーID %s の予定書き込みに失敗しました: %v", userMapping.UserID, err)
+			failed = append(failed, userMapping.UserID)
+			continue
+		}
+		planned = append(planned, result)
+	}
+
+	// シートごとにすべてのユーザーのセルをまとめて書き込み
+	failures := destinations.Flush(ctx)
+	for _, result := range planned {
+		if err := failures[result.User.UserID]; err != nil {
+			log.Printf("警告: ユーザーID %s の予定書き込みに失敗しました: %v", result.User.UserID, err)
+			failed = append(failed, result.User.UserID)
 			continue
 		}
 
-		log.Printf("ユーザーID %s の予定を正常に書き込みました（%d件）", userMapping.UserID, len(events))
+		log.Printf("ユーザーID %s の予定を正常に書き込みました（%d件）", result.User.UserID, len(result.Events))
+		completed = append(completed, result.User.UserID)
+	}
+
+	if !*dryRun {
+		if err := writeState.Save(); err != nil {
+			log.Printf("警告: 書き込み記録の保存に失敗しました: %v", err)
+		}
+	}
+
+	log.Printf("Google Sheets APIの利用状況: %v", sheetsAPI.Metrics())
+	reportRun(completed, failed, nil)
+	if ctx.Err() != nil {
+		log.Printf("処理が中断されました: %v", ctx.Err())
+		os.Exit(1)
+	}
+
+	// ドライランの結果を出力（変更がある場合は終了コード2）
+	if *dryRun {
+		if err := writeOptions.Report.Print(os.Stdout, *dryRunFormat); err != nil {
+			log.Fatal("ドライランの結果の出力に失敗しました:", err)
+		}
+		if writeOptions.Report.HasChanges() {
+			os.Exit(2)
+		}
+	}
+}
+
+// reportRun は実行結果（完了・失敗・未処理のユーザー）をログに出力します
+func reportRun(completed, failed []string, remaining []mapping.UserMapping) {
+	log.Printf("完了したユーザー（%d人）: %s", len(completed), strings.Join(completed, ","))
+	if len(failed) > 0 {
+		log.Printf("失敗したユーザー（%d人）: %s", len(failed), strings.Join(failed, ","))
+	}
+	if len(remaining) > 0 {
+		ids := make([]string, 0, len(remaining))
+		for _, m := range remaining {
+			ids = append(ids, m.UserID)
+		}
+		log.Printf("未処理のユーザー（%d人）: %s", len(ids), strings.Join(ids, ","))
+	}
+}
+
+// resolveConcurrency は予定を同時に取得するユーザー数を決定します
+// コマンドラインオプション、環境変数の順に優先し、どちらも未指定の場合はデフォルト値を使用します
+func resolveConcurrency(flagValue int, envValue string) (int, error) {
+	if flagValue != 0 {
+		if flagValue < 1 {
+			return 0, fmt.Errorf("--concurrency には1以上を指定してください: %d", flagValue)
+		}
+		return flagValue, nil
+	}
+
+	if envValue == "" {
+		return defaultConcurrency, nil
+	}
+
+	n, err := strconv.Atoi(envValue)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("GAROON_CONCURRENCYの値が不正です: %q", envValue)
+	}
+	return n, nil
+}
+
+// usersOf は取得結果のユーザーの一覧を返します
+func usersOf(results []FetchResult) []mapping.UserMapping {
+	users := make([]mapping.UserMapping, 0, len(results))
+	for _, r := range results {
+		users = append(users, r.User)
 	}
+	return users
 }
 
 // validateRequiredEnv は必須の環境変数を検証します
@@ -116,9 +401,11 @@ func validateRequiredEnv() error {
 		"GAROON_BASE_URL":             os.Getenv("GAROON_BASE_URL"),
 		"GAROON_USERNAME":             os.Getenv("GAROON_USERNAME"),
 		"GAROON_PASSWORD":             os.Getenv("GAROON_PASSWORD"),
-		"SPREADSHEET_ID":              os.Getenv("SPREADSHEET_ID"),
 		"GOOGLE_SERVICE_ACCOUNT_FILE": os.Getenv("GOOGLE_SERVICE_ACCOUNT_FILE"),
-		"USER_MAPPING_PATH":           os.Getenv("USER_MAPPING_PATH"),
+	}
+	// ユーザーは USER_MAPPING_PATH のCSVまたは USER_SOURCE_ORGS の組織から取得する
+	if os.Getenv("USER_SOURCE_ORGS") == "" {
+		required["USER_MAPPING_PATH"] = os.Getenv("USER_MAPPING_PATH")
 	}
 
 	var missingVars []string
@@ -150,61 +437,92 @@ func calculateDateRange() (time.Time, time.Time) {
 	now := time.Now()
 	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
 
-	endYear, endMonth := now.Year(), now.Month()+3
-	if endMonth > 12 {
-		endYear++
-		endMonth = endMonth - 12
-	}
-	endDate := time.Date(endYear, endMonth+1, 1, 0, 0, 0, 0, time.Local).Add(-time.Second)
+	return startDate, defaultEndDate(startDate)
+}
 
-	return startDate, endDate
+// defaultEndDate は開始日の月から3ヶ月後の月末（23:59:59）を返します
+func defaultEndDate(startDate time.Time) time.Time {
+	return time.Date(startDate.Year(), startDate.Month()+4, 1, 0, 0, 0, 0, time.Local).Add(-time.Second)
 }
 
-// SaveToSheet は予定をスプレッドシートに保存します
-func SaveToSheet(srv *sheets.Service, spreadsheetID string, events []client.Event, holidayMenus []string, userName string) error {
-	// スケジュール書き込み用のインスタンスを作成
-	writer, err := NewScheduleWriter()
-	if err != nil {
-		return fmt.Errorf("schedule writerの作成に失敗しました: %v", err)
-	}
-	writer.holidayMenus = holidayMenus
-	writer.name = userName // ユーザー名を設定
+// resolveDateRange はコマンドラインオプションから取得対象の期間を決定します
+// 未指定の場合は calculateDateRange の期間を使用し、終了日は当日の終わりまでを含みます
+func resolveDateRange(startStr, endStr string) (time.Time, time.Time, error) {
+	startDate, endDate := calculateDateRange()
 
-	// イベントを日付でグループ化
-	eventsByDate := make(map[string]map[int][]client.Event)
-	for _, e := range events {
-		eventTime, err := time.Parse(time.RFC3339, e.Start.DateTime)
+	if startStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", startStr, time.Local)
 		if err != nil {
-			log.Printf("イベントの日時解析に失敗しました: %v", err)
-			continue
+			return time.Time{}, time.Time{}, fmt.Errorf("開始日 %q の解析に失敗しました: %v", startStr, err)
 		}
+		startDate = d
+		endDate = defaultEndDate(startDate)
+	}
 
-		// シート名を取得
-		sheetMapper, err := NewSheetMapper()
+	if endStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", endStr, time.Local)
 		if err != nil {
-			return fmt.Errorf("sheet mapperの作成に失敗しました: %v", err)
+			return time.Time{}, time.Time{}, fmt.Errorf("終了日 %q の解析に失敗しました: %v", endStr, err)
 		}
+		endDate = d.AddDate(0, 0, 1).Add(-time.Second)
+	}
 
-		targetSheet := sheetMapper.GetSheetName(eventTime)
-		if targetSheet == nil {
-			continue
-		}
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("終了日 %s が開始日 %s より前です",
+			endDate.Format("2006-01-02"), startDate.Format("2006-01-02"))
+	}
 
-		if eventsByDate[*targetSheet] == nil {
-			eventsByDate[*targetSheet] = make(map[int][]client.Event)
+	return startDate, endDate, nil
+}
+
+// splitList はカンマ区切りの文字列を空要素を除いたスライスに変換します
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
+	}
+	return items
+}
 
-		day := eventTime.Day()
-		eventsByDate[*targetSheet][day] = append(eventsByDate[*targetSheet][day], e)
+// allUserIDs はすべての値が数値のユーザーIDかどうかを返します（ログイン名・メールアドレスを含む場合はfalse）
+func allUserIDs(keys []string) bool {
+	for _, key := range keys {
+		if _, err := strconv.ParseUint(key, 10, 64); err != nil {
+			return false
+		}
 	}
+	return true
+}
 
-	// シートごとに書き込み
-	for sheetName, dailyEvents := range eventsByDate {
-		err = writer.WriteSchedule(srv, spreadsheetID, sheetName, dailyEvents)
+// groupEventsBySheet はイベントをシート名と日ごとにグループ化します
+// 複数日にまたがるイベントは、含まれるすべての日（月をまたぐ場合は各シート）に展開します
+// within が false を返す日は含めません（nilの場合はすべての日）
+func groupEventsBySheet(sheetMapper *SheetMapper, events []client.Event, within func(day time.Time) bool) map[string]map[int][]client.Event {
+	eventsByDate := make(map[string]map[int][]client.Event)
+	for _, e := range events {
+		days, err := e.Days()
 		if err != nil {
-			return fmt.Errorf("シート %s の更新に失敗しました: %v", sheetName, err)
+			log.Printf("イベントの日時解析に失敗しました: %v", err)
+			continue
 		}
-	}
 
-	return nil
+		for _, day := range days {
+			if within != nil && !within(day) {
+				continue
+			}
+
+			targetSheet := sheetMapper.GetSheetName(day)
+			if targetSheet == nil {
+				continue
+			}
+
+			if eventsByDate[*targetSheet] == nil {
+				eventsByDate[*targetSheet] = make(map[int][]client.Event)
+			}
+			eventsByDate[*targetSheet][day.Day()] = append(eventsByDate[*targetSheet][day.Day()], e)
+		}
+	}
+	return eventsByDate
 }
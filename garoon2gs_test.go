@@ -0,0 +1,181 @@
+package main
+
+import (
+	"github.com/eotel/garoon2gs/internal/client"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveDateRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		start         string
+		end           string
+		expectedStart time.Time
+		expectedEnd   time.Time
+		expectError   bool
+	}{
+		{
+			name:          "開始日と終了日を指定",
+			start:         "2025-01-01",
+			end:           "2025-12-31",
+			expectedStart: time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+			expectedEnd:   time.Date(2025, 12, 31, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:          "開始日のみ指定した場合は3ヶ月後の月末まで",
+			start:         "2025-11-15",
+			expectedStart: time.Date(2025, 11, 15, 0, 0, 0, 0, time.Local),
+			expectedEnd:   time.Date(2026, 2, 28, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:        "終了日が開始日より前",
+			start:       "2025-05-01",
+			end:         "2025-04-30",
+			expectError: true,
+		},
+		{
+			name:        "不正な日付形式",
+			start:       "2025/05/01",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := resolveDateRange(tt.start, tt.end)
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !start.Equal(tt.expectedStart) {
+				t.Errorf("expected start %v but got %v", tt.expectedStart, start)
+			}
+			if !end.Equal(tt.expectedEnd) {
+				t.Errorf("expected end %v but got %v", tt.expectedEnd, end)
+			}
+		})
+	}
+}
+
+func TestResolveDateRangeDefault(t *testing.T) {
+	start, end, err := resolveDateRange("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedStart, expectedEnd := calculateDateRange()
+	if !start.Equal(expectedStart) || !end.Equal(expectedEnd) {
+		t.Errorf("expected %v - %v but got %v - %v", expectedStart, expectedEnd, start, end)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := splitList(" 12345, ,67890,")
+	expected := []string{"12345", "67890"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestAllUserIDs(t *testing.T) {
+	if !allUserIDs([]string{"12345", "67890"}) || !allUserIDs(nil) {
+		t.Error("expected numeric user IDs to be treated as user IDs")
+	}
+	if allUserIDs([]string{"12345", "ito"}) || allUserIDs([]string{"ito@example.com"}) {
+		t.Error("expected login names and emails not to be treated as user IDs")
+	}
+}
+
+func TestResolveConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		flagValue   int
+		envValue    string
+		expected    int
+		expectError bool
+	}{
+		{"未指定はデフォルト", 0, "", defaultConcurrency, false},
+		{"環境変数", 0, "8", 8, false},
+		{"オプションを優先", 2, "8", 2, false},
+		{"負のオプション", -1, "", 0, true},
+		{"不正な環境変数", 0, "many", 0, true},
+		{"0の環境変数", 0, "0", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := resolveConcurrency(tt.flagValue, tt.envValue)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got %d", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != tt.expected {
+				t.Errorf("expected %d but got %d", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestGroupEventsBySheet(t *testing.T) {
+	mapper := &SheetMapper{
+		mappings: []SheetMapping{
+			{Month: time.Date(2025, 7, 1, 0, 0, 0, 0, time.Local), SheetName: "R7年度_7月"},
+			{Month: time.Date(2025, 8, 1, 0, 0, 0, 0, time.Local), SheetName: "R7年度_8月"},
+		},
+	}
+
+	events := []Event{
+		{
+			EventMenu: "夏季休暇",
+			Start:     client.EventDateTime{DateTime: "2025-07-30T00:00:00+09:00"},
+			End:       client.EventDateTime{DateTime: "2025-08-02T00:00:00+09:00"},
+			IsAllDay:  true,
+		},
+		{
+			EventMenu: "外出",
+			Start:     client.EventDateTime{DateTime: "2025-08-05T13:00:00+09:00"},
+			End:       client.EventDateTime{DateTime: "2025-08-05T15:00:00+09:00"},
+		},
+		{
+			// マッピングのない月は無視される
+			EventMenu: "出張",
+			Start:     client.EventDateTime{DateTime: "2025-09-01T09:00:00+09:00"},
+			End:       client.EventDateTime{DateTime: "2025-09-01T18:00:00+09:00"},
+		},
+	}
+
+	grouped := groupEventsBySheet(mapper, events, nil)
+
+	expected := map[string][]int{
+		"R7年度_7月": {30, 31},
+		"R7年度_8月": {1, 5},
+	}
+	if len(grouped) != len(expected) {
+		t.Fatalf("expected %d sheets but got %d: %v", len(expected), len(grouped), grouped)
+	}
+	for sheet, days := range expected {
+		if len(grouped[sheet]) != len(days) {
+			t.Errorf("sheet %s: expected days %v but got %v", sheet, days, grouped[sheet])
+		}
+		for _, day := range days {
+			if len(grouped[sheet][day]) != 1 {
+				t.Errorf("sheet %s day %d: expected 1 event but got %d", sheet, day, len(grouped[sheet][day]))
+			}
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/eotel/garoon2gs/internal/ratelimit"
+	"github.com/eotel/garoon2gs/internal/retry"
 	"github.com/joho/godotenv"
 	"golang.org/x/crypto/pkcs12"
 	"io"
@@ -26,27 +29,16 @@ type Config struct {
 	Password     string
 	CertPath     string
 	CertPassword string
+	Retry        retry.Policy // 予定取得APIの再試行方針
+	RateLimit    float64      // 1秒あたりのリクエスト数の上限（0の場合は制限なし）
 }
 
 // GaroonClient はGaroon APIクライアントを表す構造体です
 type GaroonClient struct {
-	config *Config
-	client *http.Client
-}
-
-// Event はGaroonの予定を表す構造体です
-type Event struct {
-	ID        string        `json:"id"`
-	Subject   string        `json:"subject"`
-	EventMenu string        `json:"eventMenu"`
-	Start     EventDateTime `json:"start"`
-	End       EventDateTime `json:"end"`
-}
-
-// EventDateTime はイベントの開始・終了日時を表す構造体です
-type EventDateTime struct {
-	DateTime string `json:"dateTime"`
-	TimeZone string `json:"timeZone"`
+	config  *Config
+	client  *http.Client
+	retry   retry.Policy
+	limiter *ratelimit.Limiter // 複数のgoroutineで共有するリクエスト間隔の制限
 }
 
 func (c *GaroonClient) GetHTTPClient() *http.Client {
@@ -116,6 +108,16 @@ func LoadConfig() (*Config, error) {
 		log.Println("Warning: .env ファイルが見つかりませんでした")
 	}
 
+	retryPolicy, err := loadRetryPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit, err := loadRateLimit()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		ConfigDir:    configDir,
 		BaseURL:      os.Getenv("GAROON_BASE_URL"),
@@ -123,6 +125,8 @@ func LoadConfig() (*Config, error) {
 		Password:     os.Getenv("GAROON_PASSWORD"),
 		CertPath:     filepath.Join(configDir, os.Getenv("CLIENT_CERT_PATH")),
 		CertPassword: os.Getenv("CLIENT_CERT_PASSWORD"),
+		Retry:        retryPolicy,
+		RateLimit:    rateLimit,
 	}, nil
 }
 
@@ -166,14 +170,22 @@ func NewClient(config *Config) (*GaroonClient, error) {
 		httpClient = &http.Client{Timeout: 10 * time.Second}
 	}
 
+	// 再試行方針が未設定の場合はデフォルト値を使用
+	retryPolicy := config.Retry
+	if retryPolicy.MaxAttempts == 0 {
+		retryPolicy = DefaultRetryPolicy()
+	}
+
 	return &GaroonClient{
-		config: config,
-		client: httpClient,
+		config:  config,
+		client:  httpClient,
+		retry:   retryPolicy,
+		limiter: ratelimit.PerSecond(config.RateLimit),
 	}, nil
 }
 
 // FetchEvents は指定された期間の予定を取得します
-func (c *GaroonClient) FetchEvents(startDate, endDate time.Time, targetUserID string) ([]Event, error) {
+func (c *GaroonClient) FetchEvents(ctx context.Context, startDate, endDate time.Time, targetUserID string) ([]Event, error) {
 	var allEvents []Event
 	offset := 0
 
@@ -189,7 +201,7 @@ func (c *GaroonClient) FetchEvents(startDate, endDate time.Time, targetUserID st
 		params.Add("targetType", "user")
 
 		reqURL := fmt.Sprintf("%s/api/v1/schedule/events?%s", c.config.BaseURL, params.Encode())
-		req, err := http.NewRequest("GET", reqURL, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 		if err != nil {
 			return nil, false, fmt.Errorf("リクエストの作成に失敗しました: %v", err)
 		}
@@ -198,9 +210,14 @@ func (c *GaroonClient) FetchEvents(startDate, endDate time.Time, targetUserID st
 		req.Header.Set("X-Cybozu-Authorization", auth)
 		req.Header.Set("Content-Type", "application/json")
 
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, false, err
+		}
+
 		resp, err := c.client.Do(req)
 		if err != nil {
-			return nil, false, fmt.Errorf("APIリクエストに失敗しました: %v", err)
+			// タイムアウトや接続断などの通信エラーは再試行の対象とする
+			return nil, false, &retry.Error{Err: fmt.Errorf("APIリクエストに失敗しました: %v", err)}
 		}
 		defer resp.Body.Close()
 
@@ -213,7 +230,14 @@ func (c *GaroonClient) FetchEvents(startDate, endDate time.Time, targetUserID st
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			return nil, false, fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+			apiErr := fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+			if isRetryableStatus(resp.StatusCode) {
+				return nil, false, &retry.Error{
+					Err:        apiErr,
+					RetryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+				}
+			}
+			return nil, false, apiErr
 		}
 
 		var scheduleResp struct {
@@ -228,9 +252,15 @@ func (c *GaroonClient) FetchEvents(startDate, endDate time.Time, targetUserID st
 		return scheduleResp.Events, scheduleResp.HasNext, nil
 	}
 
-	// ページング処理
+	// ページング処理（ページ単位で再試行する）
 	for {
-		events, hasNext, err := fetchPage(offset)
+		var events []Event
+		var hasNext bool
+		err := retry.Retrier{Policy: c.retry}.Do(ctx, fmt.Sprintf("ユーザーID %s の予定取得（offset=%d）", targetUserID, offset), func() error {
+			var err error
+			events, hasNext, err = fetchPage(offset)
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("予定の取得に失敗しました: %v", err)
 		}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/fakegaroon"
+	"github.com/eotel/garoon2gs/internal/retry"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eventsPath = "/api/v1/schedule/events"
+
+// newFakeClient はフェイクサーバーに接続する GaroonClient を作成します
+// 再試行の待機時間はテスト用に短くしています
+func newFakeClient(t *testing.T, srv *fakegaroon.Server) *GaroonClient {
+	t.Helper()
+
+	c, err := NewClient(&Config{
+		BaseURL:  srv.URL,
+		Username: fakegaroon.Username,
+		Password: fakegaroon.Password,
+		Retry: retry.Policy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     10 * time.Millisecond,
+			Multiplier:     2,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+// fakeEvents は2025年5月1日から1時間ごとの予定を n 件作成します
+func fakeEvents(n int) []interface{} {
+	base := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	events := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		start := base.Add(time.Duration(i) * time.Hour)
+		events = append(events, Event{
+			ID:        fmt.Sprint(i + 1),
+			EventType: EventTypeRegular,
+			EventMenu: "外出",
+			Subject:   fmt.Sprintf("予定%d", i+1),
+			Start:     EventDateTime{DateTime: start.Format(time.RFC3339), TimeZone: "Asia/Tokyo"},
+			End:       EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339), TimeZone: "Asia/Tokyo"},
+		})
+	}
+	return events
+}
+
+func TestFetchEventsPaging(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddEvents("7", fakeEvents(250)...)
+	srv.AddEvents("8", fakeEvents(3)...)
+
+	c := newFakeClient(t, srv)
+	events, err := c.FetchEvents(context.Background(),
+		time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		"7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 250 {
+		t.Fatalf("expected 250 events but got %d", len(events))
+	}
+	for i, e := range events {
+		if e.ID != fmt.Sprint(i+1) {
+			t.Fatalf("event %d: expected ID %d but got %s", i, i+1, e.ID)
+		}
+	}
+	if got := srv.Requests(eventsPath); got != 3 {
+		t.Errorf("expected 3 requests (100+100+50) but got %d", got)
+	}
+}
+
+func TestFetchEventsRange(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddEvents("7", fakeEvents(48)...) // 5月1日9時から5月3日8時まで
+
+	c := newFakeClient(t, srv)
+	events, err := c.FetchEvents(context.Background(),
+		time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 5, 2, 23, 59, 59, 0, time.UTC),
+		"7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 5月1日23時〜5月2日0時の予定は開始時刻と重なるため含まれる
+	if len(events) != 25 {
+		t.Errorf("expected 25 events but got %d", len(events))
+	}
+}
+
+func TestFetchEventsErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		fault            fakegaroon.Fault
+		expectError      string
+		expectedRequests int
+	}{
+		{
+			name:             "一時的な503は再試行して成功",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: http.StatusServiceUnavailable, Times: 2},
+			expectedRequests: 3,
+		},
+		{
+			name:             "Retry-Afterつきの429は再試行して成功",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: http.StatusTooManyRequests, RetryAfter: "0", Times: 1},
+			expectedRequests: 2,
+		},
+		{
+			name:             "500が続く場合は最大試行回数で失敗",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: http.StatusInternalServerError},
+			expectError:      "ステータスコード: 500",
+			expectedRequests: 3,
+		},
+		{
+			name:             "401は再試行しない",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: http.StatusUnauthorized},
+			expectError:      "認証エラー",
+			expectedRequests: 1,
+		},
+		{
+			name:             "496（証明書なし）は再試行しない",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: 496},
+			expectError:      "ステータスコード: 496",
+			expectedRequests: 1,
+		},
+		{
+			name:             "404は再試行しない",
+			fault:            fakegaroon.Fault{Path: eventsPath, Status: http.StatusNotFound},
+			expectError:      "ステータスコード: 404",
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakegaroon.NewServer()
+			defer srv.Close()
+			srv.AddEvents("7", fakeEvents(5)...)
+			srv.AddFault(tt.fault)
+
+			c := newFakeClient(t, srv)
+			events, err := c.FetchEvents(context.Background(),
+				time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				"7")
+
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error containing %q but got %v", tt.expectError, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(events) != 5 {
+					t.Errorf("expected 5 events but got %d", len(events))
+				}
+			}
+
+			if got := srv.Requests(eventsPath); got != tt.expectedRequests {
+				t.Errorf("expected %d requests but got %d", tt.expectedRequests, got)
+			}
+		})
+	}
+}
+
+func TestFetchEventsWrongPassword(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+
+	c := newFakeClient(t, srv)
+	c.config.Password = "wrong"
+
+	_, err := c.FetchEvents(context.Background(), time.Now(), time.Now().AddDate(0, 1, 0), "7")
+	if err == nil || !strings.Contains(err.Error(), "認証エラー") {
+		t.Errorf("expected authentication error but got %v", err)
+	}
+}
+
+func TestFetchEventsSlowResponse(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddEvents("7", fakeEvents(5)...)
+	srv.AddFault(fakegaroon.Fault{Path: eventsPath, Delay: time.Second})
+
+	c := newFakeClient(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.FetchEvents(ctx,
+		time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		"7")
+	if err == nil {
+		t.Fatal("expected error for cancelled request but got none")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected FetchEvents to stop on context deadline but it took %v", elapsed)
+	}
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// 予定の種類（eventType）
+const (
+	EventTypeRegular   = "REGULAR"   // 通常予定
+	EventTypeRepeating = "REPEATING" // 繰り返し予定
+	EventTypeAllDay    = "ALL_DAY"   // 期間予定
+)
+
+// 予定の公開方法（visibilityType）
+const (
+	VisibilityPublic             = "PUBLIC"
+	VisibilityPrivate            = "PRIVATE"
+	VisibilitySetPrivateWatchers = "SET_PRIVATE_WATCHERS"
+)
+
+// Event はGaroonの予定を表す構造体です
+type Event struct {
+	ID             string         `json:"id"`
+	EventType      string         `json:"eventType"`
+	EventMenu      string         `json:"eventMenu"`
+	Subject        string         `json:"subject"`
+	Notes          string         `json:"notes,omitempty"`
+	VisibilityType string         `json:"visibilityType,omitempty"`
+	Start          EventDateTime  `json:"start"`
+	End            EventDateTime  `json:"end"`
+	IsAllDay       bool           `json:"isAllDay"`
+	IsStartOnly    bool           `json:"isStartOnly"`
+	Attendees      []Attendee     `json:"attendees,omitempty"`
+	Facilities     []Facility     `json:"facilities,omitempty"`
+	RepeatInfo     *RepeatInfo    `json:"repeatInfo,omitempty"`
+	Creator        *EventUserInfo `json:"creator,omitempty"`
+	CreatedAt      string         `json:"createdAt,omitempty"`
+	Updater        *EventUserInfo `json:"updater,omitempty"`
+	UpdatedAt      string         `json:"updatedAt,omitempty"`
+}
+
+// EventDateTime はイベントの開始・終了日時を表す構造体です
+type EventDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// EventUserInfo は予定の作成者・更新者を表す構造体です
+type EventUserInfo struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Attendee は予定の参加者（ユーザーまたは組織）を表す構造体です
+type Attendee struct {
+	ID                 string              `json:"id"`
+	Code               string              `json:"code"`
+	Name               string              `json:"name"`
+	Type               string              `json:"type"` // "USER" または "ORGANIZATION"
+	AttendanceResponse *AttendanceResponse `json:"attendanceResponse,omitempty"`
+}
+
+// AttendanceResponse は参加者の出欠確認の回答を表す構造体です
+type AttendanceResponse struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Facility は予定で予約された施設を表す構造体です
+type Facility struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// RepeatInfo は繰り返し予定の条件を表す構造体です
+type RepeatInfo struct {
+	Type               string          `json:"type"` // "DAY", "WEEKDAY", "WEEK", "MONTH" など
+	Period             RepeatPeriod    `json:"period"`
+	Time               RepeatTime      `json:"time"`
+	TimeZone           string          `json:"timeZone"`
+	IsAllDay           bool            `json:"isAllDay"`
+	IsStartOnly        bool            `json:"isStartOnly"`
+	DayOfWeek          string          `json:"dayOfWeek,omitempty"`
+	DayOfMonth         string          `json:"dayOfMonth,omitempty"`
+	ExclusiveDateTimes []ExclusiveTime `json:"exclusiveDateTimes,omitempty"`
+}
+
+// RepeatPeriod は繰り返しの期間（YYYY-MM-DD）を表す構造体です
+type RepeatPeriod struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// RepeatTime は繰り返し予定の時刻（HH:MM:SS）を表す構造体です
+type RepeatTime struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// ExclusiveTime は繰り返し予定から除外された日時を表す構造体です
+type ExclusiveTime struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Time は日時をtime.Timeとして解析します
+func (d EventDateTime) Time() (time.Time, error) {
+	if d.DateTime == "" {
+		return time.Time{}, fmt.Errorf("日時（dateTime）が空です")
+	}
+
+	t, err := time.Parse(time.RFC3339, d.DateTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日時 %q の解析に失敗しました: %v", d.DateTime, err)
+	}
+	return t, nil
+}
+
+// StartTime は予定の開始日時を返します
+func (e Event) StartTime() (time.Time, error) {
+	return e.Start.Time()
+}
+
+// EndTime は予定の終了日時を返します
+// 終了日時のない予定（isStartOnly）は開始日時を返します
+func (e Event) EndTime() (time.Time, error) {
+	if e.IsStartOnly || e.End.DateTime == "" {
+		return e.StartTime()
+	}
+	return e.End.Time()
+}
+
+// Duration は予定の長さを返します
+func (e Event) Duration() (time.Duration, error) {
+	start, err := e.StartTime()
+	if err != nil {
+		return 0, err
+	}
+	end, err := e.EndTime()
+	if err != nil {
+		return 0, err
+	}
+	return end.Sub(start), nil
+}
+
+// IsAllDayEvent は終日予定または期間予定かどうかを返します
+func (e Event) IsAllDayEvent() bool {
+	return e.IsAllDay || e.EventType == EventTypeAllDay
+}
+
+// UpdatedTime は予定の最終更新日時を返します
+func (e Event) UpdatedTime() (time.Time, error) {
+	if e.UpdatedAt == "" {
+		return time.Time{}, fmt.Errorf("更新日時（updatedAt）が空です")
+	}
+
+	t, err := time.Parse(time.RFC3339, e.UpdatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("更新日時 %q の解析に失敗しました: %v", e.UpdatedAt, err)
+	}
+	return t, nil
+}
+
+// HasFacility は指定された名前またはコードの施設が予約されているかどうかを返します
+func (e Event) HasFacility(nameOrCode string) bool {
+	for _, f := range e.Facilities {
+		if f.Name == nameOrCode || f.Code == nameOrCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Days は予定が含まれる各日付（開始日時のタイムゾーンにおける0時）を順に返します
+// 終了日時がちょうど0時の場合は排他的な終了とみなし、その日を含めません
+func (e Event) Days() ([]time.Time, error) {
+	start, err := e.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	end, err := e.EndTime()
+	if err != nil {
+		return nil, err
+	}
+	end = end.In(start.Location())
+
+	first := startOfDay(start)
+	last := startOfDay(end)
+	if end.After(start) && end.Equal(last) {
+		last = last.AddDate(0, 0, -1)
+	}
+	if last.Before(first) {
+		last = first
+	}
+
+	var days []time.Time
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// startOfDay は指定された日時と同じ日の0時を返します
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const sampleEventJSON = `{
+	"id": "123",
+	"eventType": "ALL_DAY",
+	"eventMenu": "出張",
+	"subject": "大阪出張",
+	"notes": "新幹線で移動",
+	"visibilityType": "PUBLIC",
+	"start": {"dateTime": "2025-05-01T00:00:00+09:00", "timeZone": "Asia/Tokyo"},
+	"end": {"dateTime": "2025-05-03T23:59:59+09:00", "timeZone": "Asia/Tokyo"},
+	"isAllDay": true,
+	"isStartOnly": false,
+	"attendees": [{"id": "3", "code": "ito", "name": "伊藤", "type": "USER"}],
+	"facilities": [{"id": "10", "code": "osaka-a", "name": "大阪会議室A"}],
+	"updatedAt": "2025-04-20T10:00:00Z"
+}`
+
+func TestEventDecode(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(sampleEventJSON), &e); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+
+	if e.EventType != EventTypeAllDay || !e.IsAllDayEvent() {
+		t.Errorf("expected all-day event but got eventType=%q isAllDay=%v", e.EventType, e.IsAllDay)
+	}
+	if len(e.Attendees) != 1 || e.Attendees[0].Code != "ito" {
+		t.Errorf("unexpected attendees: %+v", e.Attendees)
+	}
+	if !e.HasFacility("大阪会議室A") || !e.HasFacility("osaka-a") || e.HasFacility("渋谷会議室") {
+		t.Errorf("unexpected facility match for %+v", e.Facilities)
+	}
+
+	updated, err := e.UpdatedTime()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated.Equal(time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected updatedAt: %v", updated)
+	}
+}
+
+func TestEventTimes(t *testing.T) {
+	tests := []struct {
+		name             string
+		event            Event
+		expectedDuration time.Duration
+		expectError      bool
+	}{
+		{
+			name: "通常予定",
+			event: Event{
+				Start: EventDateTime{DateTime: "2025-05-01T10:00:00+09:00"},
+				End:   EventDateTime{DateTime: "2025-05-01T11:30:00+09:00"},
+			},
+			expectedDuration: 90 * time.Minute,
+		},
+		{
+			name: "終了日時のない予定",
+			event: Event{
+				Start:       EventDateTime{DateTime: "2025-05-01T10:00:00+09:00"},
+				IsStartOnly: true,
+			},
+			expectedDuration: 0,
+		},
+		{
+			name:        "開始日時が空",
+			event:       Event{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.event.Duration()
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d != tt.expectedDuration {
+				t.Errorf("expected duration %v but got %v", tt.expectedDuration, d)
+			}
+		})
+	}
+}
+
+func TestEventDays(t *testing.T) {
+	tests := []struct {
+		name         string
+		start        string
+		end          string
+		expectedDays []int
+	}{
+		{
+			name:         "同じ日に終わる予定",
+			start:        "2025-05-01T10:00:00+09:00",
+			end:          "2025-05-01T11:00:00+09:00",
+			expectedDays: []int{1},
+		},
+		{
+			name:         "3日間の出張",
+			start:        "2025-05-07T09:00:00+09:00",
+			end:          "2025-05-09T18:00:00+09:00",
+			expectedDays: []int{7, 8, 9},
+		},
+		{
+			name:         "終了が0時の終日予定は終了日を含まない",
+			start:        "2025-05-07T00:00:00+09:00",
+			end:          "2025-05-09T00:00:00+09:00",
+			expectedDays: []int{7, 8},
+		},
+		{
+			name:         "23:59:59で終わる終日予定",
+			start:        "2025-05-07T00:00:00+09:00",
+			end:          "2025-05-07T23:59:59+09:00",
+			expectedDays: []int{7},
+		},
+		{
+			name:         "月をまたぐ予定",
+			start:        "2025-04-29T00:00:00+09:00",
+			end:          "2025-05-02T23:59:59+09:00",
+			expectedDays: []int{29, 30, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Event{
+				Start: EventDateTime{DateTime: tt.start},
+				End:   EventDateTime{DateTime: tt.end},
+			}
+
+			days, err := e.Days()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []int
+			for _, d := range days {
+				got = append(got, d.Day())
+			}
+			if len(got) != len(tt.expectedDays) {
+				t.Fatalf("expected days %v but got %v", tt.expectedDays, got)
+			}
+			for i := range got {
+				if got[i] != tt.expectedDays[i] {
+					t.Fatalf("expected days %v but got %v", tt.expectedDays, got)
+				}
+			}
+		})
+	}
+}
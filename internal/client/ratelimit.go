@@ -0,0 +1,25 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// defaultRateLimit は1秒あたりのリクエスト数の上限のデフォルト値です
+const defaultRateLimit = 10
+
+// loadRateLimit は環境変数 GAROON_RATE_LIMIT から1秒あたりのリクエスト数の上限を読み込みます
+// 未設定の場合はデフォルト値、0の場合は制限なしです
+func loadRateLimit() (float64, error) {
+	v := os.Getenv("GAROON_RATE_LIMIT")
+	if v == "" {
+		return defaultRateLimit, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("GAROON_RATE_LIMITの値が不正です: %q", v)
+	}
+	return f, nil
+}
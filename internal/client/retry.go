@@ -0,0 +1,29 @@
+package client
+
+import (
+	"github.com/eotel/garoon2gs/internal/retry"
+	"net/http"
+	"time"
+)
+
+// DefaultRetryPolicy はデフォルトの再試行方針を返します
+func DefaultRetryPolicy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// loadRetryPolicy は環境変数 GAROON_RETRY_* から再試行方針を読み込みます
+// 設定されていない項目はデフォルト値を使用します
+func loadRetryPolicy() (retry.Policy, error) {
+	return retry.LoadPolicy("GAROON_RETRY", DefaultRetryPolicy())
+}
+
+// isRetryableStatus は再試行すべきステータスコードかどうかを判定します
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
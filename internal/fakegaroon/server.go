@@ -0,0 +1,441 @@
+// Package fakegaroon はテスト用のGaroon REST APIのフェイクサーバーを提供します
+package fakegaroon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// フェイクサーバーが受け付ける認証情報です
+const (
+	Username = "fake-user"
+	Password = "fake-password"
+)
+
+// defaultLimit は limit が指定されていない場合の1ページの件数です
+const defaultLimit = 100
+
+// User はフェイクサーバーが返すユーザーです
+type User struct {
+	ID     string `json:"id"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Organization はフェイクサーバーが返す組織です
+type Organization struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Code     string   `json:"code,omitempty"`
+	ParentID string   `json:"parentId,omitempty"`
+	Members  []string `json:"-"` // 所属するユーザーのID
+}
+
+// Fault はリクエストに対して注入するエラーや遅延です
+type Fault struct {
+	Path       string        // 対象のパス（前方一致、空の場合はすべてのリクエスト）
+	Status     int           // 返すステータスコード（0の場合は通常のレスポンス）
+	Delay      time.Duration // レスポンスを返すまでの遅延
+	RetryAfter string        // Retry-After ヘッダーの値
+	Times      int           // 適用する回数（0の場合は常に適用）
+}
+
+// Server はGaroon REST APIのフェイクサーバーです
+// /api/v1/schedule/events、/api/v1/base/users、/api/v1/base/organizations、
+// /api/v1/base/organizations/{id}/users を offset/limit/hasNext のページングつきで提供します
+// ユーザーと組織の一覧は name パラメーターでの検索にも対応します
+type Server struct {
+	URL string
+
+	srv *httptest.Server
+
+	mu            sync.Mutex
+	events        map[string][]interface{} // ユーザーID → 予定（JSONに変換可能な値）
+	users         []User
+	organizations []Organization
+	faults        []*Fault
+	requests      map[string]int // パス → リクエスト回数
+}
+
+// NewServer はフェイクサーバーを起動します（使用後は Close を呼び出してください）
+func NewServer() *Server {
+	s := &Server{
+		events:   make(map[string][]interface{}),
+		requests: make(map[string]int),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close はフェイクサーバーを停止します
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client はフェイクサーバーに接続するHTTPクライアントを返します
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// AddEvents はユーザーの予定を追加します
+// 予定は client.Event など、start.dateTime を含むJSONに変換できる値です
+func (s *Server) AddEvents(userID string, events ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[userID] = append(s.events[userID], events...)
+}
+
+// AddUsers はユーザーを追加します
+func (s *Server) AddUsers(users ...User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, users...)
+}
+
+// AddOrganizations は組織を追加します
+func (s *Server) AddOrganizations(orgs ...Organization) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.organizations = append(s.organizations, orgs...)
+}
+
+// AddFault はエラーや遅延を注入します
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// Requests は指定されたパスへのリクエスト回数を返します
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	fault := s.takeFault(r.URL.Path)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, fault.Status, "injected error")
+			return
+		}
+	}
+
+	if !authorized(r) {
+		writeError(w, http.StatusUnauthorized, "ログイン名またはパスワードが正しくありません。")
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/api/v1/schedule/events":
+		s.handleEvents(w, r)
+	case path == "/api/v1/base/users":
+		s.handleUsers(w, r)
+	case path == "/api/v1/base/organizations":
+		s.handleOrganizations(w, r)
+	case strings.HasPrefix(path, "/api/v1/base/organizations/") && strings.HasSuffix(path, "/users"):
+		orgID := strings.TrimSuffix(strings.TrimPrefix(path, "/api/v1/base/organizations/"), "/users")
+		s.handleOrganizationUsers(w, r, orgID)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// takeFault はパスに該当する注入済みのエラーを取り出します（s.mu を保持して呼び出すこと）
+func (s *Server) takeFault(path string) *Fault {
+	for i, f := range s.faults {
+		if !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("targetType") != "user" || q.Get("target") == "" {
+		writeError(w, http.StatusBadRequest, "target and targetType=user are required")
+		return
+	}
+
+	rangeStart, err := parseTimeParam(q.Get("rangeStart"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rangeEnd, err := parseTimeParam(q.Get("rangeEnd"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	all := append([]interface{}(nil), s.events[q.Get("target")]...)
+	s.mu.Unlock()
+
+	// 期間と重なる予定のみを返す
+	var matched []interface{}
+	for _, e := range all {
+		start, end, err := eventRange(e)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !rangeStart.IsZero() && end.Before(rangeStart) {
+			continue
+		}
+		if !rangeEnd.IsZero() && start.After(rangeEnd) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+
+	page, hasNext, err := paginate(r, len(matched))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"events":  nonNil(matched[page.from:page.to]),
+		"hasNext": hasNext,
+	})
+}
+
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var all []User
+	for _, u := range s.users {
+		if matchesName(r, u.Name, u.Code) {
+			all = append(all, u)
+		}
+	}
+	s.mu.Unlock()
+
+	page, hasNext, err := paginate(r, len(all))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"users":   append([]User{}, all[page.from:page.to]...),
+		"hasNext": hasNext,
+	})
+}
+
+func (s *Server) handleOrganizations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var all []Organization
+	for _, org := range s.organizations {
+		if matchesName(r, org.Name, org.Code) {
+			all = append(all, org)
+		}
+	}
+	s.mu.Unlock()
+
+	page, hasNext, err := paginate(r, len(all))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"organizations": append([]Organization{}, all[page.from:page.to]...),
+		"hasNext":       hasNext,
+	})
+}
+
+func (s *Server) handleOrganizationUsers(w http.ResponseWriter, r *http.Request, orgID string) {
+	s.mu.Lock()
+	var org *Organization
+	for i := range s.organizations {
+		if s.organizations[i].ID == orgID {
+			org = &s.organizations[i]
+			break
+		}
+	}
+	var members []User
+	if org != nil {
+		for _, id := range org.Members {
+			for _, u := range s.users {
+				if u.ID == id {
+					members = append(members, u)
+				}
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if org == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("organization %s not found", orgID))
+		return
+	}
+
+	page, hasNext, err := paginate(r, len(members))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"users":   append([]User{}, members[page.from:page.to]...),
+		"hasNext": hasNext,
+	})
+}
+
+// matchesName は name パラメーターが表示名またはコードの一部に一致するかどうかを判定します（大文字・小文字は区別しない）
+// name パラメーターがない場合は常に一致します
+func matchesName(r *http.Request, name, code string) bool {
+	q := strings.ToLower(r.URL.Query().Get("name"))
+	return q == "" || strings.Contains(strings.ToLower(name), q) || strings.Contains(strings.ToLower(code), q)
+}
+
+// pageRange はレスポンスに含める要素の範囲です
+type pageRange struct {
+	from int
+	to   int
+}
+
+// paginate は offset/limit パラメーターから返す範囲と続きがあるかどうかを求めます
+func paginate(r *http.Request, total int) (pageRange, bool, error) {
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		return pageRange{}, false, err
+	}
+	limit, err := intParam(r, "limit", defaultLimit)
+	if err != nil {
+		return pageRange{}, false, err
+	}
+	if offset < 0 || limit <= 0 || limit > 1000 {
+		return pageRange{}, false, fmt.Errorf("invalid offset/limit: %d/%d", offset, limit)
+	}
+
+	from := min(offset, total)
+	to := min(offset+limit, total)
+	return pageRange{from: from, to: to}, to < total, nil
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return n, nil
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %v", v, err)
+	}
+	return t, nil
+}
+
+// eventRange は予定の開始・終了日時を取得します（終了がない場合は開始日時）
+func eventRange(e interface{}) (time.Time, time.Time, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	var v struct {
+		Start struct {
+			DateTime string `json:"dateTime"`
+		} `json:"start"`
+		End struct {
+			DateTime string `json:"dateTime"`
+		} `json:"end"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse event: %v", err)
+	}
+
+	start, err := time.Parse(time.RFC3339, v.Start.DateTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start of event: %v", err)
+	}
+	if v.End.DateTime == "" {
+		return start, start, nil
+	}
+	end, err := time.Parse(time.RFC3339, v.End.DateTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end of event: %v", err)
+	}
+	return start, end, nil
+}
+
+// authorized はパスワード認証のヘッダーが正しいかどうかを判定します
+func authorized(r *http.Request) bool {
+	expected := base64.StdEncoding.EncodeToString([]byte(Username + ":" + Password))
+	return r.Header.Get("X-Cybozu-Authorization") == expected
+}
+
+func nonNil(v []interface{}) []interface{} {
+	if v == nil {
+		return []interface{}{}
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError はGaroonと同じ形式のエラーレスポンスを返します
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"errorCode": strconv.Itoa(status),
+			"message":   message,
+		},
+	})
+}
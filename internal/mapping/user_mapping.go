@@ -3,16 +3,73 @@ package mapping
 import (
 	"encoding/csv"
 	"fmt"
+	"github.com/eotel/garoon2gs/users"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 // UserMapping はユーザーIDと列名のマッピングを表す構造体です
 type UserMapping struct {
-	UserID     string // Garoonのユーザーid
-	HeaderName string // スプレッドシートのヘッダーに表示される名前
+	UserID        string // Garoonのユーザーid（CSVのキーが code, email の場合は ResolveUserIDs で設定）
+	Code          string // Garoonのログイン名（CSVのキーが code の場合）
+	Email         string // Garoonのメールアドレス（CSVのキーが email の場合）
+	HeaderName    string // スプレッドシートのヘッダーに表示される名前
+	Team          string // 所属チーム（書き込み先のスプレッドシートの選択に使用、省略可能）
+	SpreadsheetID string // 書き込み先のスプレッドシートID（省略時はチームとSPREADSHEET_IDから決定）
+	Place         string // 通常の勤務地（省略時はNORMAL_PLACE）
+	WorkDays      string // 勤務する曜日（"月火水"、"mon-fri" など、省略時はすべての曜日）
+	Rules         string // このユーザーに使用するルールファイルのパス（省略時はRULES_PATH）
+}
+
+// ユーザーマッピングのCSVの1列目（ユーザーを特定するキー）に指定できる列です
+const (
+	KeyUserID = "user_id" // GaroonのユーザーID
+	KeyCode   = "code"    // Garoonのログイン名
+	KeyEmail  = "email"   // Garoonのメールアドレス
+)
+
+// keyField はCSVのキーの列に対応する UserMapping の項目を返します
+func keyField(key string) (func(m *UserMapping) *string, bool) {
+	switch key {
+	case KeyUserID:
+		return func(m *UserMapping) *string { return &m.UserID }, true
+	case KeyCode:
+		return func(m *UserMapping) *string { return &m.Code }, true
+	case KeyEmail:
+		return func(m *UserMapping) *string { return &m.Email }, true
+	default:
+		return nil, false
+	}
+}
+
+// optionalColumn はユーザーマッピングのCSVで省略可能な列です
+type optionalColumn struct {
+	name string
+	get  func(m *UserMapping) string
+	set  func(m *UserMapping, value string)
+}
+
+// optionalColumns は省略可能な列の一覧です（WriteUserMapping はこの順に出力します）
+var optionalColumns = []optionalColumn{
+	{"team", func(m *UserMapping) string { return m.Team }, func(m *UserMapping, value string) { m.Team = value }},
+	{"spreadsheet_id", func(m *UserMapping) string { return m.SpreadsheetID }, func(m *UserMapping, value string) { m.SpreadsheetID = value }},
+	{"place", func(m *UserMapping) string { return m.Place }, func(m *UserMapping, value string) { m.Place = value }},
+	{"work_days", func(m *UserMapping) string { return m.WorkDays }, func(m *UserMapping, value string) { m.WorkDays = value }},
+	{"rules", func(m *UserMapping) string { return m.Rules }, func(m *UserMapping, value string) { m.Rules = value }},
+}
+
+// findOptionalColumn は名前から省略可能な列を探します
+func findOptionalColumn(name string) (optionalColumn, bool) {
+	for _, c := range optionalColumns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return optionalColumn{}, false
 }
 
 // LoadUserMapping はCSVファイルからユーザーマッピングを読み込みます
@@ -24,26 +81,56 @@ func LoadUserMapping(configDir string) ([]UserMapping, error) {
 	}
 
 	// CSVファイルの絶対パスを構築
-	csvPath := filepath.Join(configDir, csvPathFromEnv)
+	mappings, _, err := LoadUserMappingFile(filepath.Join(configDir, csvPathFromEnv))
+	return mappings, err
+}
 
+// LoadUserMappingFile は指定されたCSVファイルからユーザーマッピングと、ユーザーを特定するキーの列名を読み込みます
+func LoadUserMappingFile(csvPath string) ([]UserMapping, string, error) {
 	// CSVファイルを開く
 	file, err := os.Open(csvPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open user mapping CSV file %s: %v", csvPath, err)
+		return nil, "", fmt.Errorf("failed to open user mapping CSV file %s: %v", csvPath, err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
+	mappings, key, err := ReadUserMapping(file)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// ヘッダーを読み飛ばす
+	log.Printf("Loaded %d user mappings from %s", len(mappings), csvPath)
+	return mappings, key, nil
+}
+
+// ReadUserMapping はユーザーマッピングのCSVを読み込みます
+// 先頭の2列はユーザーを特定するキー（user_id, code, email のいずれか）と name で、
+// 3列目以降には省略可能な列（team, spreadsheet_id, place, work_days, rules）を任意の順で指定できます
+// ユーザーマッピングとともに、キーの列名を返します
+func ReadUserMapping(r io.Reader) ([]UserMapping, string, error) {
+	reader := csv.NewReader(r)
+
+	// ヘッダーを読み込む
 	header, err := reader.Read()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+		return nil, "", fmt.Errorf("failed to read CSV header: %v", err)
 	}
 
 	// ヘッダーの検証
-	if len(header) != 2 || header[0] != "user_id" || header[1] != "name" {
-		return nil, fmt.Errorf("invalid CSV header format: expected [user_id,name] but got %v", header)
+	if len(header) < 2 || header[1] != "name" {
+		return nil, "", fmt.Errorf("invalid CSV header format: expected [user_id,name] but got %v", header)
+	}
+	key, ok := keyField(header[0])
+	if !ok {
+		return nil, "", fmt.Errorf("invalid CSV header format: the first column must be user_id, code or email but got %q", header[0])
+	}
+	setters := make([]func(m *UserMapping, value string), len(header))
+	for i, column := range header[2:] {
+		c, ok := findOptionalColumn(column)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown column %q in CSV header", column)
+		}
+		setters[i+2] = c.set
 	}
 
 	var mappings []UserMapping
@@ -55,22 +142,25 @@ func LoadUserMapping(configDir string) ([]UserMapping, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read CSV record: %v", err)
+			return nil, "", fmt.Errorf("failed to read CSV record: %v", err)
 		}
 
-		if len(record) != 2 {
-			return nil, fmt.Errorf("invalid CSV format: expected 2 columns but got %d", len(record))
+		if len(record) != len(header) {
+			return nil, "", fmt.Errorf("invalid CSV format: expected %d columns but got %d", len(header), len(record))
 		}
 
 		// UserMappingを作成（name_colをHeaderNameとして保存）
-		mappings = append(mappings, UserMapping{
-			UserID:     record[0],
+		m := UserMapping{
 			HeaderName: record[1],
-		})
+		}
+		*key(&m) = strings.TrimSpace(record[0])
+		for i, value := range record[2:] {
+			setters[i+2](&m, strings.TrimSpace(value))
+		}
+		mappings = append(mappings, m)
 	}
 
-	log.Printf("Loaded %d user mappings from %s", len(mappings), csvPath)
-	return mappings, nil
+	return mappings, header[0], nil
 }
 
 // GetColumnForUser は指定されたユーザーIDに対応するヘッダー名を返します
@@ -82,3 +172,215 @@ func GetColumnForUser(mappings []UserMapping, userID string) (string, bool) {
 	}
 	return "", false
 }
+
+// FilterUserMappings は指定されたユーザーのマッピングのみを元の順序で返します
+// ユーザーはユーザーID、ログイン名（code）、メールアドレス（email、大文字・小文字を区別しない）のいずれかで指定できます
+// ログイン名・メールアドレスで指定する場合は、それらが設定されるよう ResolveUserIDs の後に呼び出します
+// マッピングに存在しないユーザーや、複数のユーザーに一致する指定が含まれる場合はエラーを返します
+func FilterUserMappings(mappings []UserMapping, keys []string) ([]UserMapping, error) {
+	matches := make(map[string][]string, len(keys)) // 指定 → 一致したユーザーID
+	var filtered []UserMapping
+	for _, m := range mappings {
+		matched := false
+		for _, key := range keys {
+			if m.matches(key) && !slices.Contains(matches[key], m.UserID) {
+				matches[key] = append(matches[key], m.UserID)
+				matched = true
+			}
+		}
+		if matched {
+			filtered = append(filtered, m)
+		}
+	}
+
+	var unknown, ambiguous []string
+	reported := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		switch ids := matches[key]; {
+		case len(ids) == 0:
+			unknown = append(unknown, key)
+		case len(ids) > 1:
+			ambiguous = append(ambiguous, fmt.Sprintf("%s (IDs %s)", key, strings.Join(ids, ", ")))
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("users not found in user mapping: %s", strings.Join(unknown, ","))
+	}
+	if len(ambiguous) > 0 {
+		return nil, fmt.Errorf("users matching multiple users in user mapping: %s", strings.Join(ambiguous, "; "))
+	}
+
+	return filtered, nil
+}
+
+// matches は key がユーザーID、ログイン名、メールアドレス（大文字・小文字を区別しない）のいずれかに一致するかどうかを返します
+func (m UserMapping) matches(key string) bool {
+	return key == m.UserID ||
+		(m.Code != "" && key == m.Code) ||
+		(m.Email != "" && strings.EqualFold(key, m.Email))
+}
+
+// WriteUserMapping はユーザーマッピングを、key（user_id, code, email のいずれか）を1列目としてCSVで出力します
+// 省略可能な列は、いずれかのユーザーに値がある列のみ出力します
+func WriteUserMapping(w io.Writer, mappings []UserMapping, key string) error {
+	keyOf, ok := keyField(key)
+	if !ok {
+		return fmt.Errorf("invalid key column %q (expected user_id, code or email)", key)
+	}
+
+	var columns []optionalColumn
+	for _, c := range optionalColumns {
+		for i := range mappings {
+			if c.get(&mappings[i]) != "" {
+				columns = append(columns, c)
+				break
+			}
+		}
+	}
+
+	writer := csv.NewWriter(w)
+
+	header := []string{key, "name"}
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %v", err)
+	}
+
+	for i := range mappings {
+		if *keyOf(&mappings[i]) == "" {
+			return fmt.Errorf("user %s (%s) has no %s", mappings[i].UserID, mappings[i].HeaderName, key)
+		}
+		record := []string{*keyOf(&mappings[i]), mappings[i].HeaderName}
+		for _, c := range columns {
+			record = append(record, c.get(&mappings[i]))
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %v", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MergeUserMapping は既存のユーザーマッピングに members を統合します
+// 既存のユーザーはヘッダー名などの設定を維持し、members にない新しいユーザーを末尾に追加します
+// prune が true の場合は members にいない既存のユーザーを削除します（false の場合は残します）
+// 追加したユーザーと、members にいない既存のユーザーを返します
+func MergeUserMapping(existing, members []UserMapping, prune bool) (merged, added, removed []UserMapping) {
+	memberIDs := make(map[string]bool, len(members))
+	for _, m := range members {
+		memberIDs[m.UserID] = true
+	}
+
+	existingIDs := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		existingIDs[m.UserID] = true
+		if !memberIDs[m.UserID] {
+			removed = append(removed, m)
+			if prune {
+				continue
+			}
+		}
+		merged = append(merged, m)
+	}
+
+	for _, m := range members {
+		if existingIDs[m.UserID] {
+			continue
+		}
+		existingIDs[m.UserID] = true
+		merged = append(merged, m)
+		added = append(added, m)
+	}
+	return merged, added, removed
+}
+
+// ResolveUserIDs はキーが code または email のユーザーマッピングのユーザーIDを、Garoonのユーザー一覧から求めます
+// あわせて、空の Code と Email をユーザー一覧の値で補完します
+// 一致するユーザーがいない、複数のユーザーに一致する、または同じユーザーが複数回記載されている場合は、
+// 該当するすべての行をエラーで報告します
+func ResolveUserIDs(mappings []UserMapping, userList []users.User) ([]UserMapping, error) {
+	resolved := make([]UserMapping, 0, len(mappings))
+	var problems []string
+	rowsByID := make(map[string][]string)
+
+	usersByID := make(map[string]users.User, len(userList))
+	for _, u := range userList {
+		usersByID[u.ID] = u
+	}
+
+	for _, m := range mappings {
+		ref := "user_id=" + m.UserID
+		if m.UserID == "" {
+			var matches []users.User
+			for _, u := range userList {
+				switch {
+				case m.Code != "" && u.Code == m.Code:
+					matches = append(matches, u)
+				case m.Email != "" && strings.EqualFold(u.Email, m.Email):
+					matches = append(matches, u)
+				}
+			}
+
+			ref = "code=" + m.Code
+			if m.Email != "" {
+				ref = "email=" + m.Email
+			}
+			switch len(matches) {
+			case 0:
+				problems = append(problems, fmt.Sprintf("%s: user not found", ref))
+				continue
+			case 1:
+				m.UserID = matches[0].ID
+			default:
+				ids := make([]string, 0, len(matches))
+				for _, u := range matches {
+					ids = append(ids, u.ID)
+				}
+				problems = append(problems, fmt.Sprintf("%s: matches multiple users (IDs %s)", ref, strings.Join(ids, ", ")))
+				continue
+			}
+		}
+
+		if u, ok := usersByID[m.UserID]; ok {
+			if m.Code == "" {
+				m.Code = u.Code
+			}
+			if m.Email == "" {
+				m.Email = u.Email
+			}
+		}
+
+		rowsByID[m.UserID] = append(rowsByID[m.UserID], ref)
+		resolved = append(resolved, m)
+	}
+
+	for _, m := range resolved {
+		if refs := rowsByID[m.UserID]; len(refs) > 1 {
+			problems = append(problems, fmt.Sprintf("user ID %s is listed more than once (%s)", m.UserID, strings.Join(refs, ", ")))
+			delete(rowsByID, m.UserID)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("failed to resolve user mapping:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return resolved, nil
+}
+
+// NeedsUserIDs はユーザーIDが未設定の（キーが code または email の）ユーザーマッピングがあるかどうかを返します
+func NeedsUserIDs(mappings []UserMapping) bool {
+	for _, m := range mappings {
+		if m.UserID == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package mapping
+
+import (
+	"github.com/eotel/garoon2gs/users"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadUserMapping(t *testing.T) {
+	tests := []struct {
+		name        string
+		csv         string
+		expected    []UserMapping
+		key         string // 空の場合は user_id
+		expectError bool
+	}{
+		{
+			name: "user_id and name",
+			csv:  "user_id,name\n1,伊藤\n2,三浦\n",
+			expected: []UserMapping{
+				{UserID: "1", HeaderName: "伊藤"},
+				{UserID: "2", HeaderName: "三浦"},
+			},
+		},
+		{
+			name: "team and spreadsheet_id",
+			csv:  "user_id,name,spreadsheet_id,team\n1,伊藤,,営業\n2,三浦,book-x, \n",
+			expected: []UserMapping{
+				{UserID: "1", HeaderName: "伊藤", Team: "営業"},
+				{UserID: "2", HeaderName: "三浦", SpreadsheetID: "book-x"},
+			},
+		},
+		{
+			name: "per-user settings",
+			csv:  "user_id,name,place,work_days,rules\n1,伊藤,大阪,月水金,rules/part_time.json\n2,三浦,,,\n",
+			expected: []UserMapping{
+				{UserID: "1", HeaderName: "伊藤", Place: "大阪", WorkDays: "月水金", Rules: "rules/part_time.json"},
+				{UserID: "2", HeaderName: "三浦"},
+			},
+		},
+		{
+			name: "code key",
+			csv:  "code,name\nito,伊藤\n",
+			key:  KeyCode,
+			expected: []UserMapping{
+				{Code: "ito", HeaderName: "伊藤"},
+			},
+		},
+		{
+			name: "email key",
+			csv:  "email,name,team\n ito@example.com ,伊藤,営業\n",
+			key:  KeyEmail,
+			expected: []UserMapping{
+				{Email: "ito@example.com", HeaderName: "伊藤", Team: "営業"},
+			},
+		},
+		{
+			name:        "invalid header",
+			csv:         "id,name\n1,伊藤\n",
+			expectError: true,
+		},
+		{
+			name:        "unknown column",
+			csv:         "user_id,name,group\n1,伊藤,営業\n",
+			expectError: true,
+		},
+		{
+			name:        "missing column",
+			csv:         "user_id,name,team\n1,伊藤\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, key, err := ReadUserMapping(strings.NewReader(tt.csv))
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, got)
+			}
+			expectedKey := tt.key
+			if expectedKey == "" {
+				expectedKey = KeyUserID
+			}
+			if key != expectedKey {
+				t.Errorf("expected key %q but got %q", expectedKey, key)
+			}
+		})
+	}
+}
+
+func TestWriteUserMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []UserMapping
+		key      string
+		expected string
+	}{
+		{
+			name:     "user_id and name",
+			mappings: []UserMapping{{UserID: "1", HeaderName: "伊藤"}, {UserID: "2", HeaderName: "三浦"}},
+			key:      KeyUserID,
+			expected: "user_id,name\n1,伊藤\n2,三浦\n",
+		},
+		{
+			name:     "optional columns in use",
+			mappings: []UserMapping{{UserID: "1", HeaderName: "伊藤", SpreadsheetID: "book-x"}, {UserID: "2", HeaderName: "三浦"}},
+			key:      KeyUserID,
+			expected: "user_id,name,spreadsheet_id\n1,伊藤,book-x\n2,三浦,\n",
+		},
+		{
+			name:     "code key",
+			mappings: []UserMapping{{Code: "ito", HeaderName: "伊藤"}},
+			key:      KeyCode,
+			expected: "code,name\nito,伊藤\n",
+		},
+	}
+
+	var out strings.Builder
+	if err := WriteUserMapping(&out, []UserMapping{{UserID: "1", HeaderName: "伊藤"}}, KeyEmail); err == nil {
+		t.Error("expected error for user without email but got none")
+	}
+	if err := WriteUserMapping(&out, nil, "name"); err == nil {
+		t.Error("expected error for invalid key column but got none")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := WriteUserMapping(&out, tt.mappings, tt.key); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, out.String())
+			}
+
+			// 出力したCSVは読み込める
+			got, _, err := ReadUserMapping(strings.NewReader(out.String()))
+			if err != nil || !reflect.DeepEqual(got, tt.mappings) {
+				t.Errorf("round trip: expected %+v but got %+v (err: %v)", tt.mappings, got, err)
+			}
+		})
+	}
+}
+
+func TestMergeUserMapping(t *testing.T) {
+	existing := []UserMapping{
+		{UserID: "1", HeaderName: "伊藤（営業）", Team: "営業"},
+		{UserID: "2", HeaderName: "三浦"},
+	}
+	members := []UserMapping{
+		{UserID: "3", HeaderName: "佐藤 花子"},
+		{UserID: "1", HeaderName: "伊藤 太郎"},
+	}
+
+	tests := []struct {
+		name     string
+		prune    bool
+		expected []UserMapping
+	}{
+		{
+			name:     "keep removed members",
+			expected: []UserMapping{existing[0], existing[1], members[0]},
+		},
+		{
+			name:     "prune removed members",
+			prune:    true,
+			expected: []UserMapping{existing[0], members[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, added, removed := MergeUserMapping(existing, members, tt.prune)
+			if !reflect.DeepEqual(merged, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, merged)
+			}
+			if len(added) != 1 || added[0].UserID != "3" {
+				t.Errorf("expected user 3 to be added but got %+v", added)
+			}
+			if len(removed) != 1 || removed[0].UserID != "2" {
+				t.Errorf("expected user 2 to be removed but got %+v", removed)
+			}
+		})
+	}
+}
+
+func TestResolveUserIDs(t *testing.T) {
+	userList := []users.User{
+		{ID: "1", Code: "ito", Name: "伊藤 太郎", Email: "ito@example.com"},
+		{ID: "2", Code: "miura", Name: "三浦 花子", Email: "miura@example.com"},
+		{ID: "3", Code: "sato", Name: "佐藤 一郎", Email: "sales@example.com"},
+		{ID: "4", Code: "sato2", Name: "佐藤 次郎", Email: "sales@example.com"},
+	}
+
+	resolved, err := ResolveUserIDs([]UserMapping{
+		{UserID: "3", HeaderName: "佐藤"},
+		{Code: "ito", HeaderName: "伊藤"},
+		{Email: "Miura@Example.com", HeaderName: "三浦", Team: "営業"},
+	}, userList)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []UserMapping{
+		{UserID: "3", Code: "sato", Email: "sales@example.com", HeaderName: "佐藤"},
+		{UserID: "1", Code: "ito", Email: "ito@example.com", HeaderName: "伊藤"},
+		{UserID: "2", Code: "miura", Email: "Miura@Example.com", HeaderName: "三浦", Team: "営業"},
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("expected %+v but got %+v", expected, resolved)
+	}
+
+	tests := []struct {
+		name     string
+		mappings []UserMapping
+		expected string
+	}{
+		{"unknown code", []UserMapping{{Code: "tanaka", HeaderName: "田中"}}, "code=tanaka: user not found"},
+		{"ambiguous email", []UserMapping{{Email: "sales@example.com", HeaderName: "営業"}}, "email=sales@example.com: matches multiple users (IDs 3, 4)"},
+		{"duplicate user", []UserMapping{{UserID: "1", HeaderName: "伊藤"}, {Code: "ito", HeaderName: "伊藤2"}}, "user ID 1 is listed more than once (user_id=1, code=ito)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveUserIDs(tt.mappings, userList)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestFilterUserMappings(t *testing.T) {
+	mappings := []UserMapping{
+		{UserID: "1", Code: "ito", Email: "ito@example.com", HeaderName: "伊藤"},
+		{UserID: "2", Code: "miura", Email: "sales@example.com", HeaderName: "三浦"},
+		{UserID: "3", Code: "sato", Email: "Sato@Example.com", HeaderName: "佐藤"},
+		{UserID: "4", Code: "tanaka", Email: "sales@example.com", HeaderName: "田中"},
+	}
+
+	tests := []struct {
+		name        string
+		userIDs     []string
+		expected    []string
+		expectError string
+	}{
+		{
+			name:     "マッピングの順序を維持する",
+			userIDs:  []string{"3", "1"},
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "重複した指定",
+			userIDs:  []string{"2", "2"},
+			expected: []string{"2"},
+		},
+		{
+			name:     "ログイン名とメールアドレス（大文字・小文字を区別しない）",
+			userIDs:  []string{"sato@example.com", "ito", "2"},
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:        "複数のユーザーに一致するメールアドレス",
+			userIDs:     []string{"sales@example.com"},
+			expectError: "users matching multiple users in user mapping: sales@example.com (IDs 2, 4)",
+		},
+		{
+			name:        "存在しないID",
+			userIDs:     []string{"9", "1", "8", "9", "ITO"},
+			expectError: "users not found in user mapping: 9,8,ITO",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := FilterUserMappings(mappings, tt.userIDs)
+			if tt.expectError != "" {
+				if err == nil || err.Error() != tt.expectError {
+					t.Errorf("expected error %q but got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, m := range filtered {
+				ids = append(ids, m.UserID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package pagination は offset/limit/hasNext でページングされるGaroon REST APIの一覧を取得するための関数を提供します
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// PageSize は1回のリクエストで取得する件数です
+const PageSize = 100
+
+// FetchAll は path の一覧を hasNext が false になるまでページごとに取得し、
+// レスポンスの key の配列をすべて連結して返します
+// params には offset/limit 以外のクエリパラメーター（検索条件など）を指定します（nilの場合はなし）
+func FetchAll[T any](ctx context.Context, client *http.Client, baseURL, path, username, password string, params url.Values, key string) ([]T, error) {
+	var all []T
+	offset := 0
+	for {
+		items, hasNext, err := fetchPage[T](ctx, client, baseURL, path, username, password, params, key, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		// 続きがあるのに空のページが返された場合は、無限ループにならないよう終了する
+		if !hasNext || len(items) == 0 {
+			break
+		}
+		offset += len(items)
+	}
+	return all, nil
+}
+
+// fetchPage は offset から1ページ分の一覧を取得します
+func fetchPage[T any](ctx context.Context, client *http.Client, baseURL, path, username, password string, params url.Values, key string, offset int) ([]T, bool, error) {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(PageSize))
+
+	reqURL := fmt.Sprintf("%s%s?%s", baseURL, path, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("リクエストの作成に失敗しました: %v", err)
+	}
+
+	// Basic認証ヘッダーの設定
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
+	req.Header.Set("X-Cybozu-Authorization", auth)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("APIリクエストに失敗しました: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, false, fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+	}
+
+	// 一覧のキーはAPIごとに異なる（"users", "organizations" など）ため、先にキーごとに分けて読み取る
+	var response map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, false, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+	}
+
+	var items []T
+	if raw, ok := response[key]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, false, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+		}
+	}
+
+	var hasNext bool
+	if raw, ok := response["hasNext"]; ok {
+		if err := json.Unmarshal(raw, &hasNext); err != nil {
+			return nil, false, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+		}
+	}
+	return items, hasNext, nil
+}
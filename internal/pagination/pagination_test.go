@@ -0,0 +1,116 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	ID string `json:"id"`
+}
+
+func TestFetchAll(t *testing.T) {
+	tests := []struct {
+		name        string
+		pages       []string // offset順のレスポンス
+		expected    []string
+		requests    int
+		expectError string
+	}{
+		{
+			name:     "single page",
+			pages:    []string{`{"items": [{"id": "1"}, {"id": "2"}], "hasNext": false}`},
+			expected: []string{"1", "2"},
+			requests: 1,
+		},
+		{
+			name: "multiple pages",
+			pages: []string{
+				`{"items": [{"id": "1"}, {"id": "2"}], "hasNext": true}`,
+				`{"items": [{"id": "3"}], "hasNext": false}`,
+			},
+			expected: []string{"1", "2", "3"},
+			requests: 2,
+		},
+		{
+			name:     "empty page with hasNext",
+			pages:    []string{`{"items": [], "hasNext": true}`},
+			expected: nil,
+			requests: 1,
+		},
+		{
+			name:     "missing key",
+			pages:    []string{`{"hasNext": false}`},
+			expected: nil,
+			requests: 1,
+		},
+		{
+			name:        "invalid JSON",
+			pages:       []string{`{"items": "x"}`},
+			requests:    1,
+			expectError: "JSONのデコードに失敗しました",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries = append(queries, r.URL.Query())
+				fmt.Fprint(w, tt.pages[min(len(queries), len(tt.pages))-1])
+			}))
+			defer srv.Close()
+
+			params := url.Values{"name": {"伊藤"}}
+			items, err := FetchAll[item](context.Background(), srv.Client(), srv.URL, "/api/v1/items", "user", "pass", params, "items")
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error containing %q but got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, it := range items {
+				ids = append(ids, it.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, ids)
+			}
+
+			if len(queries) != tt.requests {
+				t.Fatalf("expected %d requests but got %d", tt.requests, len(queries))
+			}
+			for i, q := range queries {
+				if q.Get("name") != "伊藤" || q.Get("limit") != "100" {
+					t.Errorf("request %d: unexpected query %v", i, q)
+				}
+			}
+			if len(queries) > 1 && queries[1].Get("offset") != "2" {
+				t.Errorf("expected offset 2 for the second page but got %q", queries[1].Get("offset"))
+			}
+		})
+	}
+}
+
+func TestFetchAllError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error": {"message": "forbidden"}}`)
+	}))
+	defer srv.Close()
+
+	_, err := FetchAll[item](context.Background(), srv.Client(), srv.URL, "/api/v1/items", "user", "pass", nil, "items")
+	if err == nil || !strings.Contains(err.Error(), "ステータスコード: 403") {
+		t.Errorf("expected error containing status code but got %v", err)
+	}
+}
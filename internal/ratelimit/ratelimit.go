@@ -0,0 +1,85 @@
+// Package ratelimit はAPIリクエストの流量を制限する Limiter を提供します
+// Garoon APIとGoogle Sheets APIで同じ流量制限の挙動になるよう、この実装を共有します
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limiter は直近 window の間に送信したリクエスト数を limit 以下に保つための構造体です
+// 複数のgoroutineから同時に使用できます。nilの Limiter は制限しません
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time // 送信した（または送信を予約した）時刻
+}
+
+// New は window あたり limit 回までリクエストを許可する Limiter を作成します
+// limit が0以下の場合は制限しない（nilを返す）
+func New(limit int, window time.Duration) *Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &Limiter{limit: limit, window: window}
+}
+
+// PerSecond は1秒あたり perSecond 回までリクエストを許可する Limiter を作成します
+// リクエストの間隔を 1/perSecond 秒以上に保ちます。perSecond が0以下の場合は制限しない（nilを返す）
+func PerSecond(perSecond float64) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return New(1, time.Duration(float64(time.Second)/perSecond))
+}
+
+// Reserve はリクエストの送信枠を予約し、送信まで待機すべき時間を返します
+func (l *Limiter) Reserve(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// window より前に送信したリクエストは数えない
+	expired := 0
+	for expired < len(l.sent) && !l.sent[expired].After(now.Add(-l.window)) {
+		expired++
+	}
+	l.sent = l.sent[expired:]
+
+	if len(l.sent) < l.limit {
+		l.sent = append(l.sent, now)
+		return 0
+	}
+
+	// limit 件前のリクエストが window の外に出るまで待機する
+	at := l.sent[len(l.sent)-l.limit].Add(l.window)
+	if last := l.sent[len(l.sent)-1]; at.Before(last) {
+		at = last
+	}
+	l.sent = append(l.sent, at)
+	return at.Sub(now)
+}
+
+// Wait は次のリクエストを送信できるまで待機します
+// ctx がキャンセルされた場合は待機を中断してエラーを返します
+func (l *Limiter) Wait(ctx context.Context) error {
+	wait := l.Reserve(time.Now())
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("リクエストの待機中に中断されました: %v", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	l := New(2, time.Minute)
+	start := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		at       time.Duration
+		expected time.Duration
+	}{
+		{0, 0},
+		{10 * time.Second, 0},
+		{20 * time.Second, 40 * time.Second}, // 1回目が1分前になるまで
+		{30 * time.Second, 40 * time.Second}, // 2回目が1分前になるまで（1分10秒）
+		{3 * time.Minute, 0},
+	}
+
+	for i, s := range steps {
+		if wait := l.Reserve(start.Add(s.at)); wait != s.expected {
+			t.Errorf("step %d: expected wait %v but got %v", i+1, s.expected, wait)
+		}
+	}
+}
+
+func TestPerSecondReserve(t *testing.T) {
+	l := PerSecond(2) // 500msごとに1回
+	start := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	// 同時に送信しようとしたリクエストは500msずつずらして送信する
+	expected := []time.Duration{0, 500 * time.Millisecond, time.Second}
+	for i, want := range expected {
+		if wait := l.Reserve(start); wait != want {
+			t.Errorf("request %d: expected wait %v but got %v", i+1, want, wait)
+		}
+	}
+
+	// 間隔が空いていれば待機しない
+	if wait := l.Reserve(start.Add(2 * time.Second)); wait != 0 {
+		t.Errorf("expected no wait after the interval but got %v", wait)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter := PerSecond(100) // 10msごとに1回
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 1回目はすぐに通過し、残りの5回は10msずつ待機する
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected requests to be spaced by the rate limit but took only %v", elapsed)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	limiter := PerSecond(1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("expected error for cancelled wait but got none")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	if limiter := PerSecond(0); limiter != nil {
+		t.Errorf("expected nil limiter for 0 but got %+v", limiter)
+	}
+	if limiter := New(0, time.Minute); limiter != nil {
+		t.Errorf("expected nil limiter for 0 but got %+v", limiter)
+	}
+
+	var limiter *Limiter
+	if wait := limiter.Reserve(time.Now()); wait != 0 {
+		t.Errorf("expected no wait but got %v", wait)
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
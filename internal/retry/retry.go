@@ -0,0 +1,183 @@
+// Package retry はAPIリクエストの再試行方針と、再試行までの待機時間の計算を提供します
+// Garoon APIとGoogle Sheets APIで同じ再試行の挙動になるよう、この実装を共有します
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Policy はAPIリクエストの再試行方針を表す構造体です
+type Policy struct {
+	MaxAttempts    int           // 最大試行回数（初回を含む）
+	InitialBackoff time.Duration // 1回目の再試行までの待機時間
+	MaxBackoff     time.Duration // 待機時間の上限
+	Multiplier     float64       // 再試行ごとの待機時間の倍率
+	Jitter         float64       // 待機時間に加えるゆらぎの割合（0〜1）
+}
+
+// LoadPolicy は環境変数 <prefix>_MAX_ATTEMPTS・<prefix>_INITIAL_BACKOFF・<prefix>_MAX_BACKOFF・<prefix>_JITTER から再試行方針を読み込みます
+// 設定されていない項目は policy の値を使用します
+func LoadPolicy(prefix string, policy Policy) (Policy, error) {
+	if v := os.Getenv(prefix + "_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return policy, fmt.Errorf("%s_MAX_ATTEMPTSの値が不正です: %q", prefix, v)
+		}
+		policy.MaxAttempts = n
+	}
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{prefix + "_INITIAL_BACKOFF", &policy.InitialBackoff},
+		{prefix + "_MAX_BACKOFF", &policy.MaxBackoff},
+	}
+	for _, e := range durations {
+		v := os.Getenv(e.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return policy, fmt.Errorf("%sの値が不正です: %q", e.name, v)
+		}
+		*e.value = d
+	}
+
+	if v := os.Getenv(prefix + "_JITTER"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			return policy, fmt.Errorf("%s_JITTERの値が不正です: %q", prefix, v)
+		}
+		policy.Jitter = f
+	}
+
+	return policy, nil
+}
+
+// Backoff は attempt 回目（1始まり）の失敗後に待機する時間を計算します
+// 待機時間は再試行ごとに Multiplier 倍になり、上限で止めた後に Jitter の割合のゆらぎを加えます
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(rand.Float64()*2-1)
+	}
+
+	return time.Duration(d)
+}
+
+// Error は再試行によって回復する可能性のあるエラーです
+type Error struct {
+	Err        error
+	RetryAfter time.Duration // サーバーから指定された待機時間（Retry-After）
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ParseRetryAfter はRetry-Afterヘッダーの値（秒数またはHTTP日付）を待機時間に変換します
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// Retrier は再試行方針に従って処理を再試行する構造体です
+type Retrier struct {
+	Policy  Policy
+	Sleep   func(ctx context.Context, d time.Duration) error // 待機に使う関数（nilの場合は Sleep）
+	OnRetry func()                                           // 再試行の前に呼び出す関数（nilの場合は呼び出さない）
+}
+
+// Do は op を実行し、op が *Error を返した場合のみ再試行します
+// 待機時間は Retry-After が指定されていればその値、なければ Policy.Backoff の値です
+// ctx がキャンセルされた時点で中断します
+func (r Retrier) Do(ctx context.Context, desc string, op func() error) error {
+	maxAttempts := max(r.Policy.MaxAttempts, 1)
+	sleep := r.Sleep
+	if sleep == nil {
+		sleep = Sleep
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+
+		// キャンセル・期限切れによる失敗は再試行しない
+		if ctx.Err() != nil {
+			return err
+		}
+
+		var re *Error
+		if !errors.As(err, &re) || attempt >= maxAttempts {
+			return err
+		}
+
+		wait := r.Policy.Backoff(attempt)
+		if re.RetryAfter > 0 {
+			wait = re.RetryAfter
+		}
+
+		if r.OnRetry != nil {
+			r.OnRetry()
+		}
+		log.Printf("%s に失敗しました（%d/%d回目）。%v 後に再試行します: %v", desc, attempt, maxAttempts, wait.Round(time.Millisecond), err)
+
+		if err := sleep(ctx, wait); err != nil {
+			return fmt.Errorf("再試行の待機中に中断されました: %v", err)
+		}
+	}
+}
+
+// Sleep は d の間待機します。ctx がキャンセルされた場合は待機を中断してエラーを返します
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,206 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"空", "", 0},
+		{"秒数", "120", 2 * time.Minute},
+		{"0秒", "0", 0},
+		{"負の秒数", "-5", 0},
+		{"HTTP日付", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"過去のHTTP日付", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"不正な値", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRetryAfter(tt.value, now); got != tt.expected {
+				t.Errorf("expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicyBackoff(t *testing.T) {
+	policy := Policy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+
+	// ゆらぎなしの場合は倍増し、上限で止まる
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want {
+			t.Errorf("attempt %d: expected %v but got %v", i+1, want, got)
+		}
+	}
+
+	// ゆらぎは待機時間に対する割合で加える（上限で止めた後に加える）
+	policy.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("expected backoff within 2s±20%% but got %v", got)
+		}
+		if got := policy.Backoff(10); got < 4*time.Second || got > 6*time.Second {
+			t.Fatalf("expected capped backoff within 5s±20%% but got %v", got)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	defaults := Policy{MaxAttempts: 4, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    Policy
+		expectError bool
+	}{
+		{
+			name:     "未指定",
+			env:      map[string]string{},
+			expected: defaults,
+		},
+		{
+			name: "すべて指定",
+			env: map[string]string{
+				"TEST_RETRY_MAX_ATTEMPTS":    "6",
+				"TEST_RETRY_INITIAL_BACKOFF": "500ms",
+				"TEST_RETRY_MAX_BACKOFF":     "1m",
+				"TEST_RETRY_JITTER":          "0",
+			},
+			expected: Policy{MaxAttempts: 6, InitialBackoff: 500 * time.Millisecond, MaxBackoff: time.Minute, Multiplier: 2, Jitter: 0},
+		},
+		{"試行回数が0", map[string]string{"TEST_RETRY_MAX_ATTEMPTS": "0"}, Policy{}, true},
+		{"試行回数が数値でない", map[string]string{"TEST_RETRY_MAX_ATTEMPTS": "many"}, Policy{}, true},
+		{"負の待機時間", map[string]string{"TEST_RETRY_INITIAL_BACKOFF": "-1s"}, Policy{}, true},
+		{"単位のない待機時間", map[string]string{"TEST_RETRY_MAX_BACKOFF": "30"}, Policy{}, true},
+		{"ゆらぎが1を超える", map[string]string{"TEST_RETRY_JITTER": "1.5"}, Policy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"TEST_RETRY_MAX_ATTEMPTS", "TEST_RETRY_INITIAL_BACKOFF", "TEST_RETRY_MAX_BACKOFF", "TEST_RETRY_JITTER"} {
+				t.Setenv(k, tt.env[k])
+			}
+
+			policy, err := LoadPolicy("TEST_RETRY", defaults)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy != tt.expected {
+				t.Errorf("expected %+v but got %+v", tt.expected, policy)
+			}
+		})
+	}
+}
+
+// newTestRetrier は待機せずに待機時間を記録する Retrier を作成します
+func newTestRetrier(policy Policy) (Retrier, *[]time.Duration) {
+	var waits []time.Duration
+	r := Retrier{
+		Policy: policy,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return ctx.Err()
+		},
+	}
+	return r, &waits
+}
+
+func TestRetrierDo(t *testing.T) {
+	policy := Policy{MaxAttempts: 3, InitialBackoff: time.Second, Multiplier: 2}
+
+	t.Run("Retry-Afterの待機時間を優先する", func(t *testing.T) {
+		r, waits := newTestRetrier(policy)
+		calls := 0
+		err := r.Do(context.Background(), "テスト", func() error {
+			calls++
+			if calls == 1 {
+				return &Error{Err: fmt.Errorf("429"), RetryAfter: ParseRetryAfter("7", time.Now())}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls != 2 {
+			t.Errorf("expected 2 calls but got %d", calls)
+		}
+		if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+			t.Errorf("expected a single 7s wait (Retry-After) but got %v", *waits)
+		}
+	})
+
+	t.Run("Retry-Afterがない場合はバックオフで待機する", func(t *testing.T) {
+		r, waits := newTestRetrier(policy)
+		retried := 0
+		r.OnRetry = func() { retried++ }
+		calls := 0
+		err := r.Do(context.Background(), "テスト", func() error {
+			calls++
+			return &Error{Err: fmt.Errorf("503")}
+		})
+		if err == nil {
+			t.Fatal("expected error but got none")
+		}
+		if calls != 3 || retried != 2 {
+			t.Errorf("expected 3 calls and 2 retries but got %d calls, %d retries", calls, retried)
+		}
+		if len(*waits) != 2 || (*waits)[0] != time.Second || (*waits)[1] != 2*time.Second {
+			t.Errorf("expected waits [1s 2s] but got %v", *waits)
+		}
+	})
+
+	t.Run("待機中にキャンセルされた場合は中断する", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		start := time.Now()
+		err := Retrier{Policy: policy}.Do(ctx, "テスト", func() error {
+			calls++
+			return &Error{Err: fmt.Errorf("429"), RetryAfter: time.Minute}
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("expected cancellation after 1 call but got %d calls, %v", calls, err)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("expected to stop waiting on cancellation but waited %v", elapsed)
+		}
+	})
+
+	t.Run("再試行できないエラーは再試行しない", func(t *testing.T) {
+		r, waits := newTestRetrier(policy)
+		calls := 0
+		permanent := errors.New("401")
+		err := r.Do(context.Background(), "テスト", func() error {
+			calls++
+			return permanent
+		})
+		if !errors.Is(err, permanent) || calls != 1 || len(*waits) != 0 {
+			t.Errorf("expected 1 call returning the error but got %d calls, %v", calls, err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"strings"
+	"time"
+)
+
+// HalfDay は半休（午前休・午後休）を判定するための設定です
+// 休暇ルール（leave: true）に一致した予定がコアタイムのどれだけを占めるかで判定します
+type HalfDay struct {
+	Start          string  `json:"start"`          // コアタイムの開始（"HH:MM"、デフォルト: "09:00"）
+	LunchStart     string  `json:"lunchStart"`     // 昼休みの開始（デフォルト: "12:00"）
+	LunchEnd       string  `json:"lunchEnd"`       // 昼休みの終了（デフォルト: "13:00"）
+	End            string  `json:"end"`            // コアタイムの終了（デフォルト: "18:00"）
+	MinCoverage    float64 `json:"minCoverage"`    // 半日を休みとみなす割合（0〜1、デフォルト: 0.75）
+	MorningLabel   string  `json:"morningLabel"`   // 午前休のラベル（{status}は午後の判定結果、デフォルト: "午前休/{status}"）
+	AfternoonLabel string  `json:"afternoonLabel"` // 午後休のラベル（{status}は午前の判定結果、デフォルト: "午後休/{status}"）
+
+	start      int
+	lunchStart int
+	lunchEnd   int
+	end        int
+}
+
+// coverage は休暇の予定が1日のどの部分を占めるかを表します
+type coverage int
+
+const (
+	coverNone      coverage = iota // 半日に満たない（時間休など）
+	coverMorning                   // 午前のみ
+	coverAfternoon                 // 午後のみ
+	coverFullDay                   // 終日
+)
+
+// compile は未指定の項目にデフォルト値を設定し、時刻を解析します
+func (h *HalfDay) compile() error {
+	setDefault := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	setDefault(&h.Start, "09:00")
+	setDefault(&h.LunchStart, "12:00")
+	setDefault(&h.LunchEnd, "13:00")
+	setDefault(&h.End, "18:00")
+	setDefault(&h.MorningLabel, "午前休/{status}")
+	setDefault(&h.AfternoonLabel, "午後休/{status}")
+	if h.MinCoverage == 0 {
+		h.MinCoverage = 0.75
+	}
+	if h.MinCoverage < 0 || h.MinCoverage > 1 {
+		return fmt.Errorf("minCoverage must be between 0 and 1 but got %v", h.MinCoverage)
+	}
+
+	var err error
+	for _, f := range []struct {
+		name  string
+		value string
+		dest  *int
+	}{
+		{"start", h.Start, &h.start},
+		{"lunchStart", h.LunchStart, &h.lunchStart},
+		{"lunchEnd", h.LunchEnd, &h.lunchEnd},
+		{"end", h.End, &h.end},
+	} {
+		if *f.dest, err = parseClock(f.value); err != nil {
+			return fmt.Errorf("invalid %s: %v", f.name, err)
+		}
+	}
+
+	if !(h.start < h.lunchStart && h.lunchStart <= h.lunchEnd && h.lunchEnd < h.end) {
+		return fmt.Errorf("core hours must satisfy start < lunchStart <= lunchEnd < end")
+	}
+	return nil
+}
+
+// label は午前休・午後休のラベルに残りの時間帯のステータスを埋め込みます
+func (h *HalfDay) label(c coverage, status string) string {
+	format := h.MorningLabel
+	if c == coverAfternoon {
+		format = h.AfternoonLabel
+	}
+	return strings.ReplaceAll(format, "{status}", status)
+}
+
+// coverage は休暇の予定が指定日のコアタイムをどれだけ占めるかを判定します
+// 終日予定や日時を解析できない予定は終日の休暇とみなします
+func (h *HalfDay) coverage(day time.Time, events []client.Event) coverage {
+	var morning, afternoon time.Duration
+	var morningLen, afternoonLen time.Duration
+
+	for _, e := range events {
+		if e.IsAllDayEvent() {
+			return coverFullDay
+		}
+		start, errStart := e.StartTime()
+		end, errEnd := e.EndTime()
+		if errStart != nil || errEnd != nil || day.IsZero() {
+			return coverFullDay
+		}
+
+		// コアタイムは予定のタイムゾーンで評価する
+		loc := start.Location()
+		at := func(minutes int) time.Time {
+			return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, loc)
+		}
+		morningLen = at(h.lunchStart).Sub(at(h.start))
+		afternoonLen = at(h.end).Sub(at(h.lunchEnd))
+
+		morning += overlap(start, end, at(h.start), at(h.lunchStart))
+		afternoon += overlap(start, end, at(h.lunchEnd), at(h.end))
+	}
+
+	if morningLen == 0 || afternoonLen == 0 {
+		return coverNone
+	}
+
+	offMorning := float64(morning)/float64(morningLen) >= h.MinCoverage
+	offAfternoon := float64(afternoon)/float64(afternoonLen) >= h.MinCoverage
+
+	switch {
+	case offMorning && offAfternoon:
+		return coverFullDay
+	case offMorning:
+		return coverMorning
+	case offAfternoon:
+		return coverAfternoon
+	default:
+		return coverNone
+	}
+}
+
+// overlap は2つの期間が重なる長さを返します
+func overlap(start, end, windowStart, windowEnd time.Time) time.Duration {
+	if start.Before(windowStart) {
+		start = windowStart
+	}
+	if end.After(windowEnd) {
+		end = windowEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
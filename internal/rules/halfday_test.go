@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"github.com/eotel/garoon2gs/internal/client"
+	"testing"
+)
+
+func TestEvaluateHalfDay(t *testing.T) {
+	rs := FromMenus([]string{"年次休暇", "時間休暇"}, []string{"外出"}, "渋谷")
+
+	tests := []struct {
+		name     string
+		events   []client.Event
+		expected string
+	}{
+		{
+			name:     "午前休",
+			events:   []client.Event{event("年次休暇", "午前休", "2025-05-01T09:00:00+09:00", "2025-05-01T13:00:00+09:00")},
+			expected: "午前休/渋谷",
+		},
+		{
+			name:     "午後休",
+			events:   []client.Event{event("年次休暇", "午後休", "2025-05-01T13:00:00+09:00", "2025-05-01T18:00:00+09:00")},
+			expected: "午後休/渋谷",
+		},
+		{
+			name: "午後休と午前の外出",
+			events: []client.Event{
+				event("外出", "客先", "2025-05-01T09:30:00+09:00", "2025-05-01T12:00:00+09:00"),
+				event("年次休暇", "午後休", "2025-05-01T13:00:00+09:00", "2025-05-01T18:00:00+09:00"),
+			},
+			expected: "午後休/外出",
+		},
+		{
+			name:     "1時間の時間休暇は休みにしない",
+			events:   []client.Event{event("時間休暇", "通院", "2025-05-01T09:00:00+09:00", "2025-05-01T10:00:00+09:00")},
+			expected: "渋谷",
+		},
+		{
+			name: "午前と午後の休暇を合わせて終日",
+			events: []client.Event{
+				event("年次休暇", "午前休", "2025-05-01T09:00:00+09:00", "2025-05-01T12:00:00+09:00"),
+				event("時間休暇", "午後", "2025-05-01T13:00:00+09:00", "2025-05-01T18:00:00+09:00"),
+			},
+			expected: "週休",
+		},
+		{
+			name:     "コアタイム全体の休暇",
+			events:   []client.Event{event("年次休暇", "", "2025-05-01T09:00:00+09:00", "2025-05-01T18:00:00+09:00")},
+			expected: "週休",
+		},
+		{
+			name: "複数日の休暇の途中の日",
+			events: []client.Event{
+				event("年次休暇", "", "2025-04-30T13:00:00+09:00", "2025-05-02T12:00:00+09:00"),
+			},
+			expected: "週休",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rs.Evaluate(testDay, tt.events); got != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHalfDayConfig(t *testing.T) {
+	rs, err := Parse([]byte(`{
+		"default": "本社",
+		"halfDay": {
+			"start": "10:00", "lunchStart": "13:00", "lunchEnd": "14:00", "end": "19:00",
+			"minCoverage": 0.5,
+			"morningLabel": "AM休", "afternoonLabel": "PM休"
+		},
+		"rules": [{"name": "休暇", "eventMenus": ["休暇"], "leave": true, "label": "休"}]
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		event    client.Event
+		expected string
+	}{
+		{
+			name:     "コアタイムの半分以上で午前休",
+			event:    event("休暇", "", "2025-05-01T10:00:00+09:00", "2025-05-01T11:30:00+09:00"),
+			expected: "AM休",
+		},
+		{
+			name:     "コアタイムの半分未満",
+			event:    event("休暇", "", "2025-05-01T15:00:00+09:00", "2025-05-01T17:00:00+09:00"),
+			expected: "本社",
+		},
+		{
+			name:     "午後のコアタイムの半分以上",
+			event:    event("休暇", "", "2025-05-01T15:00:00+09:00", "2025-05-01T18:00:00+09:00"),
+			expected: "PM休",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rs.Evaluate(testDay, []client.Event{tt.event}); got != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHalfDayInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"コアタイムの順序が不正", `{"halfDay": {"start": "13:00", "lunchStart": "12:00"}, "rules": []}`},
+		{"割合が範囲外", `{"halfDay": {"minCoverage": 1.5}, "rules": []}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.rules)); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
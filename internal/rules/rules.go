@@ -0,0 +1,303 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RuleSet はセルに書き込むステータスを決定するルールの集合です
+type RuleSet struct {
+	Default string  `json:"default"` // どのルールにも一致しない場合のラベル
+	HalfDay HalfDay `json:"halfDay"` // 半休の判定設定
+	Rules   []Rule  `json:"rules"`
+}
+
+// Rule はイベントの条件と、一致した場合に出力するラベルを表す構造体です
+// 指定された条件はすべて満たす必要があります（未指定の条件は判定しません）
+type Rule struct {
+	Name        string   `json:"name"`
+	Priority    int      `json:"priority"`              // 大きいほど優先（同じ場合はファイル内の順序）
+	EventMenus  []string `json:"eventMenus,omitempty"`  // 予定メニューのいずれかに一致
+	Subject     string   `json:"subject,omitempty"`     // タイトルの正規表現
+	MinDuration string   `json:"minDuration,omitempty"` // 予定の長さの下限（例: "2h"）
+	MaxDuration string   `json:"maxDuration,omitempty"` // 予定の長さの上限（例: "1h30m"）
+	AllDay      *bool    `json:"allDay,omitempty"`      // 終日予定かどうか
+	StartAfter  string   `json:"startAfter,omitempty"`  // 開始時刻がこの時刻以降（"HH:MM"）
+	StartBefore string   `json:"startBefore,omitempty"` // 開始時刻がこの時刻より前（"HH:MM"）
+	EndAfter    string   `json:"endAfter,omitempty"`    // 終了時刻がこの時刻より後（"HH:MM"）
+	EndBefore   string   `json:"endBefore,omitempty"`   // 終了時刻がこの時刻以前（"HH:MM"）
+	Facilities  []string `json:"facilities,omitempty"`  // 施設名またはコードのいずれかを予約
+	Leave       bool     `json:"leave,omitempty"`       // 休暇ルール（コアタイムに対する割合で半休・時間休を判定）
+	Label       string   `json:"label"`
+
+	subject     *regexp.Regexp
+	minDuration time.Duration
+	maxDuration time.Duration
+	startAfter  int // 0時からの経過分
+	startBefore int
+	endAfter    int
+	endBefore   int
+}
+
+// Load はJSONファイルからルールを読み込みます
+func Load(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file %s: %v", path, err)
+	}
+
+	rs, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	return rs, nil
+}
+
+// Parse はJSONからルールを読み込み、検証します
+func Parse(data []byte) (*RuleSet, error) {
+	var rs RuleSet
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %v", err)
+	}
+
+	if err := rs.compile(); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// FromMenus は HOLIDAY_MENUS / OUTING_MENUS の設定から従来と同じ判定を行うルールを作成します
+func FromMenus(holidayMenus, outingMenus []string, normalPlace string) *RuleSet {
+	rs := &RuleSet{Default: normalPlace}
+	if len(holidayMenus) > 0 {
+		rs.Rules = append(rs.Rules, Rule{Name: "休暇", Priority: 200, EventMenus: holidayMenus, Leave: true, Label: "週休"})
+	}
+	if len(outingMenus) > 0 {
+		rs.Rules = append(rs.Rules, Rule{Name: "外出", Priority: 100, EventMenus: outingMenus, Label: "外出"})
+	}
+
+	// デフォルトのコアタイムのみのため失敗しない
+	_ = rs.HalfDay.compile()
+	return rs
+}
+
+// compile は各ルールの条件を解析し、優先度順に並べ替えます
+func (rs *RuleSet) compile() error {
+	if err := rs.HalfDay.compile(); err != nil {
+		return fmt.Errorf("halfDay: %v", err)
+	}
+
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule#%d", i+1)
+		}
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+	}
+
+	sort.SliceStable(rs.Rules, func(i, j int) bool {
+		return rs.Rules[i].Priority > rs.Rules[j].Priority
+	})
+	return nil
+}
+
+func (r *Rule) compile() error {
+	if r.Label == "" {
+		return fmt.Errorf("label is required")
+	}
+
+	var err error
+	if r.Subject != "" {
+		if r.subject, err = regexp.Compile(r.Subject); err != nil {
+			return fmt.Errorf("invalid subject pattern: %v", err)
+		}
+	}
+	if r.MinDuration != "" {
+		if r.minDuration, err = time.ParseDuration(r.MinDuration); err != nil {
+			return fmt.Errorf("invalid minDuration: %v", err)
+		}
+	}
+	if r.MaxDuration != "" {
+		if r.maxDuration, err = time.ParseDuration(r.MaxDuration); err != nil {
+			return fmt.Errorf("invalid maxDuration: %v", err)
+		}
+	}
+
+	for _, f := range []struct {
+		name  string
+		value string
+		dest  *int
+	}{
+		{"startAfter", r.StartAfter, &r.startAfter},
+		{"startBefore", r.StartBefore, &r.startBefore},
+		{"endAfter", r.EndAfter, &r.endAfter},
+		{"endBefore", r.EndBefore, &r.endBefore},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if *f.dest, err = parseClock(f.value); err != nil {
+			return fmt.Errorf("invalid %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+// parseClock は "HH:MM" 形式の時刻を0時からの経過分に変換します
+func parseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("expected HH:MM but got %q", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// minuteOfDay は時刻を0時からの経過分に変換します
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// Matches はイベントがルールのすべての条件を満たすかどうかを判定します
+func (r *Rule) Matches(e client.Event) bool {
+	if len(r.EventMenus) > 0 && !contains(r.EventMenus, e.EventMenu) {
+		return false
+	}
+	if r.subject != nil && !r.subject.MatchString(e.Subject) {
+		return false
+	}
+	if r.AllDay != nil && *r.AllDay != e.IsAllDayEvent() {
+		return false
+	}
+	if len(r.Facilities) > 0 {
+		found := false
+		for _, f := range r.Facilities {
+			if e.HasFacility(f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// 以下は日時の条件のため、日時を解析できないイベントは一致しない
+	if r.MinDuration != "" || r.MaxDuration != "" {
+		d, err := e.Duration()
+		if err != nil {
+			return false
+		}
+		if r.MinDuration != "" && d < r.minDuration {
+			return false
+		}
+		if r.MaxDuration != "" && d > r.maxDuration {
+			return false
+		}
+	}
+	if r.StartAfter != "" || r.StartBefore != "" {
+		start, err := e.StartTime()
+		if err != nil {
+			return false
+		}
+		m := minuteOfDay(start)
+		if r.StartAfter != "" && m < r.startAfter {
+			return false
+		}
+		if r.StartBefore != "" && m >= r.startBefore {
+			return false
+		}
+	}
+	if r.EndAfter != "" || r.EndBefore != "" {
+		end, err := e.EndTime()
+		if err != nil {
+			return false
+		}
+		m := minuteOfDay(end)
+		if r.EndAfter != "" && m <= r.endAfter {
+			return false
+		}
+		if r.EndBefore != "" && m > r.endBefore {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Evaluate は指定日のイベントから、優先度が最も高い一致ルールのラベルを返します
+// 一致するルールがない場合（イベントがない場合を含む）はデフォルトのラベルを返します
+//
+// 休暇ルールは一致した予定がコアタイムを占める割合で判定し、半日分の場合は
+// 残りの時間帯を休暇の予定を除いて判定した結果と組み合わせたラベル（"午前休/渋谷" など）を返します。
+// 半日に満たない休暇（時間休など）は一致しなかったものとして扱います。
+func (rs *RuleSet) Evaluate(day time.Time, events []client.Event) string {
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+
+		var matched []client.Event
+		for _, e := range events {
+			if r.Matches(e) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		if !r.Leave {
+			return r.Label
+		}
+
+		switch c := rs.HalfDay.coverage(day, matched); c {
+		case coverFullDay:
+			return r.Label
+		case coverMorning, coverAfternoon:
+			return rs.HalfDay.label(c, rs.Evaluate(day, rs.withoutLeave(events)))
+		}
+	}
+	return rs.Default
+}
+
+// withoutLeave は休暇ルールに一致する予定を除いたイベントを返します
+func (rs *RuleSet) withoutLeave(events []client.Event) []client.Event {
+	var rest []client.Event
+	for _, e := range events {
+		leave := false
+		for i := range rs.Rules {
+			if rs.Rules[i].Leave && rs.Rules[i].Matches(e) {
+				leave = true
+				break
+			}
+		}
+		if !leave {
+			rest = append(rest, e)
+		}
+	}
+	return rest
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
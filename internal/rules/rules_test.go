@@ -0,0 +1,148 @@
+package rules
+
+import (
+	"github.com/eotel/garoon2gs/internal/client"
+	"testing"
+	"time"
+)
+
+var testDay = time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)
+
+const testRules = `{
+	"default": "渋谷",
+	"rules": [
+		{"name": "外出", "priority": 100, "eventMenus": ["外出", "出張"], "minDuration": "2h", "label": "外出"},
+		{"name": "休暇", "priority": 200, "eventMenus": ["年次休暇"], "label": "週休"},
+		{"name": "大阪出張", "priority": 150, "eventMenus": ["出張"], "subject": "大阪", "label": "出張(大阪)"},
+		{"name": "在宅", "priority": 120, "subject": "在宅", "allDay": true, "label": "在宅"},
+		{"name": "朝の施設利用", "priority": 110, "facilities": ["studio-a"], "startBefore": "12:00", "label": "スタジオ"}
+	]
+}`
+
+func event(menu, subject, start, end string) client.Event {
+	return client.Event{
+		EventMenu: menu,
+		Subject:   subject,
+		Start:     client.EventDateTime{DateTime: start},
+		End:       client.EventDateTime{DateTime: end},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rs, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	allDayRemote := event("", "在宅勤務", "2025-05-01T00:00:00+09:00", "2025-05-01T23:59:59+09:00")
+	allDayRemote.IsAllDay = true
+
+	studio := event("", "収録", "2025-05-01T10:00:00+09:00", "2025-05-01T11:00:00+09:00")
+	studio.Facilities = []client.Facility{{Code: "studio-a", Name: "スタジオA"}}
+
+	studioAfternoon := event("", "収録", "2025-05-01T14:00:00+09:00", "2025-05-01T15:00:00+09:00")
+	studioAfternoon.Facilities = studio.Facilities
+
+	tests := []struct {
+		name     string
+		events   []client.Event
+		expected string
+	}{
+		{
+			name:     "予定なし",
+			events:   nil,
+			expected: "渋谷",
+		},
+		{
+			name: "休暇は外出より優先",
+			events: []client.Event{
+				event("外出", "打ち合わせ", "2025-05-01T10:00:00+09:00", "2025-05-01T15:00:00+09:00"),
+				event("年次休暇", "", "2025-05-01T00:00:00+09:00", "2025-05-01T23:59:59+09:00"),
+			},
+			expected: "週休",
+		},
+		{
+			name:     "タイトルで出張先を判定",
+			events:   []client.Event{event("出張", "大阪支社訪問", "2025-05-01T09:00:00+09:00", "2025-05-01T18:00:00+09:00")},
+			expected: "出張(大阪)",
+		},
+		{
+			name:     "短い外出は通常勤務",
+			events:   []client.Event{event("外出", "郵便局", "2025-05-01T10:00:00+09:00", "2025-05-01T11:00:00+09:00")},
+			expected: "渋谷",
+		},
+		{
+			name:     "長い外出",
+			events:   []client.Event{event("外出", "客先", "2025-05-01T10:00:00+09:00", "2025-05-01T15:00:00+09:00")},
+			expected: "外出",
+		},
+		{
+			name:     "終日の在宅勤務",
+			events:   []client.Event{allDayRemote},
+			expected: "在宅",
+		},
+		{
+			name:     "終日でない在宅勤務は一致しない",
+			events:   []client.Event{event("", "在宅勤務", "2025-05-01T13:00:00+09:00", "2025-05-01T15:00:00+09:00")},
+			expected: "渋谷",
+		},
+		{
+			name:     "午前の施設利用",
+			events:   []client.Event{studio},
+			expected: "スタジオ",
+		},
+		{
+			name:     "午後の施設利用は一致しない",
+			events:   []client.Event{studioAfternoon},
+			expected: "渋谷",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rs.Evaluate(testDay, tt.events); got != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"ラベルなし", `{"rules": [{"eventMenus": ["外出"]}]}`},
+		{"不正な正規表現", `{"rules": [{"subject": "(", "label": "x"}]}`},
+		{"不正な期間", `{"rules": [{"minDuration": "2hours", "label": "x"}]}`},
+		{"不正な時刻", `{"rules": [{"startAfter": "9", "label": "x"}]}`},
+		{"不正なJSON", `{"rules": [`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.rules)); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestFromMenus(t *testing.T) {
+	rs := FromMenus([]string{"休暇"}, []string{"外出"}, "本社")
+
+	tests := []struct {
+		menu     string
+		expected string
+	}{
+		{"休暇", "週休"},
+		{"外出", "外出"},
+		{"会議", "本社"},
+	}
+
+	for _, tt := range tests {
+		if got := rs.Evaluate(testDay, []client.Event{{EventMenu: tt.menu}}); got != tt.expected {
+			t.Errorf("menu %s: expected %s but got %s", tt.menu, tt.expected, got)
+		}
+	}
+}
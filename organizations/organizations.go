@@ -1,12 +1,14 @@
 package organizations
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/eotel/garoon2gs/internal/pagination"
 	"github.com/eotel/garoon2gs/users"
-	"io"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // Organization represents a Garoon organization
@@ -18,70 +20,126 @@ type Organization struct {
 	Description string `json:"description,omitempty"`
 }
 
-// ListOrganizations retrieves all organizations
-func ListOrganizations(client *http.Client, baseURL, username, password string) ([]Organization, error) {
-	reqURL := fmt.Sprintf("%s/api/v1/base/organizations", baseURL)
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("リクエストの作成に失敗しました: %v", err)
-	}
+// Filter narrows down the organization list. Empty fields are not used for filtering
+type Filter struct {
+	Name string // searched with Garoon's name parameter (matches part of the organization name)
+	Code string // organization code (exact match)
+}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
-	req.Header.Set("X-Cybozu-Authorization", auth)
+// ListOrganizations retrieves all organizations, following hasNext across pages
+func ListOrganizations(ctx context.Context, client *http.Client, baseURL, username, password string) ([]Organization, error) {
+	return SearchOrganizations(ctx, client, baseURL, username, password, Filter{})
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("APIリクエストに失敗しました: %v", err)
+// SearchOrganizations retrieves the organizations matching filter
+func SearchOrganizations(ctx context.Context, client *http.Client, baseURL, username, password string, filter Filter) ([]Organization, error) {
+	params := url.Values{}
+	if filter.Name != "" {
+		params.Set("name", filter.Name)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+	all, err := pagination.FetchAll[Organization](ctx, client, baseURL, "/api/v1/base/organizations", username, password, params, "organizations")
+	if err != nil {
+		return nil, err
 	}
 
-	var response struct {
-		Organizations []Organization `json:"organizations"`
+	if filter.Code == "" {
+		return all, nil
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+	var matched []Organization
+	for _, org := range all {
+		if org.Code == filter.Code {
+			matched = append(matched, org)
+		}
 	}
+	return matched, nil
+}
 
-	return response.Organizations, nil
+// GetOrganizationUsers retrieves all users belonging to a specific organization, following hasNext across pages
+func GetOrganizationUsers(ctx context.Context, client *http.Client, baseURL, username, password, orgID string) ([]users.User, error) {
+	path := fmt.Sprintf("/api/v1/base/organizations/%s/users", url.PathEscape(orgID))
+	return pagination.FetchAll[users.User](ctx, client, baseURL, path, username, password, nil, "users")
 }
 
-// GetOrganizationUsers retrieves users belonging to a specific organization
-func GetOrganizationUsers(client *http.Client, baseURL, username, password, orgID string) ([]users.User, error) {
-	reqURL := fmt.Sprintf("%s/api/v1/base/organizations/%s/users", baseURL, orgID)
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("リクエストの作成に失敗しました: %v", err)
+// ResolveOrganizationIDs converts organization IDs or codes in refs into organization IDs.
+// It returns an error listing the references that match no organization in orgs
+func ResolveOrganizationIDs(orgs []Organization, refs []string) ([]string, error) {
+	ids := make([]string, 0, len(refs))
+	var unknown []string
+	for _, ref := range refs {
+		found := false
+		for _, org := range orgs {
+			if org.ID == ref || (org.Code != "" && org.Code == ref) {
+				ids = append(ids, org.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, ref)
+		}
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
-	req.Header.Set("X-Cybozu-Authorization", auth)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("APIリクエストに失敗しました: %v", err)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("組織が見つかりません: %s", strings.Join(unknown, ","))
 	}
-	defer resp.Body.Close()
+	return ids, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+// ExpandOrganizationIDs returns orgIDs followed by all of their descendant organizations in orgs.
+// Each ID appears only once, in breadth-first order
+func ExpandOrganizationIDs(orgs []Organization, orgIDs []string) []string {
+	children := make(map[string][]string)
+	for _, org := range orgs {
+		if org.ParentID != "" {
+			children[org.ParentID] = append(children[org.ParentID], org.ID)
+		}
 	}
 
-	var response struct {
-		Users []users.User `json:"users"`
+	seen := make(map[string]bool)
+	var expanded []string
+	queue := append([]string(nil), orgIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		expanded = append(expanded, id)
+		queue = append(queue, children[id]...)
 	}
+	return expanded
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+// GetMembers retrieves the users belonging to any of the given organizations.
+// If recursive is true, members of descendant organizations are included as well.
+// Users belonging to more than one organization are returned once, in the order they first appear
+func GetMembers(ctx context.Context, client *http.Client, baseURL, username, password string, orgIDs []string, recursive bool) ([]users.User, error) {
+	if recursive {
+		orgs, err := ListOrganizations(ctx, client, baseURL, username, password)
+		if err != nil {
+			return nil, err
+		}
+		orgIDs = ExpandOrganizationIDs(orgs, orgIDs)
 	}
 
-	return response.Users, nil
+	seen := make(map[string]bool)
+	var members []users.User
+	for _, orgID := range orgIDs {
+		orgUsers, err := GetOrganizationUsers(ctx, client, baseURL, username, password, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("組織 %s のメンバーの取得に失敗しました: %v", orgID, err)
+		}
+		for _, u := range orgUsers {
+			if seen[u.ID] {
+				continue
+			}
+			seen[u.ID] = true
+			members = append(members, u)
+		}
+	}
+	return members, nil
 }
 
 // PrintOrganizations formats and prints organization list
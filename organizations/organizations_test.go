@@ -0,0 +1,257 @@
+package organizations
+
+import (
+	"context"
+	"github.com/eotel/garoon2gs/internal/fakegaroon"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newOrganizationServer は組織とユーザーを登録したフェイクサーバーを起動します
+func newOrganizationServer() *fakegaroon.Server {
+	srv := fakegaroon.NewServer()
+	srv.AddUsers(
+		fakegaroon.User{ID: "1", Code: "ito", Name: "伊藤"},
+		fakegaroon.User{ID: "2", Code: "miura", Name: "三浦"},
+		fakegaroon.User{ID: "3", Code: "sato", Name: "佐藤"},
+	)
+	srv.AddOrganizations(
+		fakegaroon.Organization{ID: "10", Name: "開発部", Code: "dev", Members: []string{"1", "2"}},
+		fakegaroon.Organization{ID: "11", Name: "開発1課", Code: "dev1", ParentID: "10", Members: []string{"3"}},
+	)
+	return srv
+}
+
+func TestListOrganizations(t *testing.T) {
+	srv := newOrganizationServer()
+	defer srv.Close()
+
+	orgs, err := ListOrganizations(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(orgs) != 2 {
+		t.Fatalf("expected 2 organizations but got %d", len(orgs))
+	}
+	if orgs[1].ID != "11" || orgs[1].Name != "開発1課" || orgs[1].ParentID != "10" {
+		t.Errorf("unexpected organization: %+v", orgs[1])
+	}
+}
+
+func TestGetOrganizationUsers(t *testing.T) {
+	srv := newOrganizationServer()
+	defer srv.Close()
+
+	members, err := GetOrganizationUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, "10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(members) != 2 || members[0].Name != "伊藤" || members[1].Name != "三浦" {
+		t.Errorf("unexpected members: %+v", members)
+	}
+}
+
+func TestListOrganizationsPaging(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+
+	var members []string
+	for i := 1; i <= 150; i++ {
+		id := strconv.Itoa(i)
+		srv.AddUsers(fakegaroon.User{ID: id, Code: "user" + id, Name: "ユーザー" + id})
+		members = append(members, id)
+	}
+	for i := 1; i <= 120; i++ {
+		org := fakegaroon.Organization{ID: strconv.Itoa(i), Name: "組織" + strconv.Itoa(i)}
+		if i == 1 {
+			org.Members = members
+		}
+		srv.AddOrganizations(org)
+	}
+
+	orgs, err := ListOrganizations(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orgs) != 120 {
+		t.Errorf("expected 120 organizations but got %d", len(orgs))
+	}
+	if n := srv.Requests("/api/v1/base/organizations"); n != 2 {
+		t.Errorf("expected 2 requests for organizations but got %d", n)
+	}
+
+	orgUsers, err := GetOrganizationUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orgUsers) != 150 {
+		t.Errorf("expected 150 members but got %d", len(orgUsers))
+	}
+	if n := srv.Requests("/api/v1/base/organizations/1/users"); n != 2 {
+		t.Errorf("expected 2 requests for members but got %d", n)
+	}
+}
+
+func TestSearchOrganizations(t *testing.T) {
+	srv := newOrganizationServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"no filter", Filter{}, []string{"10", "11"}},
+		{"name", Filter{Name: "1課"}, []string{"11"}},
+		{"code", Filter{Code: "dev"}, []string{"10"}},
+		{"no match", Filter{Code: "sales"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs, err := SearchOrganizations(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, org := range orgs {
+				ids = append(ids, org.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestGetOrganizationUsersErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		orgID       string
+		fault       *fakegaroon.Fault
+		expectError string
+	}{
+		{
+			name:        "存在しない組織",
+			orgID:       "99",
+			expectError: "ステータスコード: 404",
+		},
+		{
+			name:        "証明書なし",
+			orgID:       "10",
+			fault:       &fakegaroon.Fault{Path: "/api/v1/base/organizations/", Status: 496},
+			expectError: "ステータスコード: 496",
+		},
+		{
+			name:        "サーバーエラー",
+			orgID:       "10",
+			fault:       &fakegaroon.Fault{Status: http.StatusServiceUnavailable},
+			expectError: "ステータスコード: 503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newOrganizationServer()
+			defer srv.Close()
+			if tt.fault != nil {
+				srv.AddFault(*tt.fault)
+			}
+
+			_, err := GetOrganizationUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, tt.orgID)
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing %q but got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestExpandOrganizationIDs(t *testing.T) {
+	orgs := []Organization{
+		{ID: "10", Name: "開発部"},
+		{ID: "11", Name: "開発1課", ParentID: "10"},
+		{ID: "12", Name: "開発2課", ParentID: "10"},
+		{ID: "13", Name: "基盤チーム", ParentID: "11"},
+		{ID: "20", Name: "営業部"},
+	}
+
+	tests := []struct {
+		name     string
+		orgIDs   []string
+		expected []string
+	}{
+		{"子組織を含む", []string{"10"}, []string{"10", "11", "12", "13"}},
+		{"子組織なし", []string{"20"}, []string{"20"}},
+		{"重複", []string{"11", "10"}, []string{"11", "10", "13", "12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExpandOrganizationIDs(orgs, tt.orgIDs)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetMembers(t *testing.T) {
+	srv := newOrganizationServer()
+	defer srv.Close()
+	srv.AddOrganizations(fakegaroon.Organization{ID: "12", Name: "開発2課", ParentID: "10", Members: []string{"1", "3"}})
+
+	tests := []struct {
+		name      string
+		recursive bool
+		expected  []string
+	}{
+		{"直下の組織のみ", false, []string{"1", "2"}},
+		{"子組織を含む", true, []string{"1", "2", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, err := GetMembers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, []string{"10"}, tt.recursive)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, m := range members {
+				ids = append(ids, m.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected members %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	if _, err := GetMembers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, []string{"99"}, false); err == nil {
+		t.Error("expected error for unknown organization but got none")
+	}
+}
+
+func TestResolveOrganizationIDs(t *testing.T) {
+	orgs := []Organization{
+		{ID: "10", Name: "開発部", Code: "dev"},
+		{ID: "11", Name: "開発1課", Code: "dev1", ParentID: "10"},
+	}
+
+	ids, err := ResolveOrganizationIDs(orgs, []string{"dev1", "10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(ids, ",") != "11,10" {
+		t.Errorf("expected [11 10] but got %v", ids)
+	}
+
+	if _, err := ResolveOrganizationIDs(orgs, []string{"dev", "sales", "99"}); err == nil || !strings.Contains(err.Error(), "sales,99") {
+		t.Errorf("expected error listing unknown organizations but got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/ratelimit"
+	"github.com/eotel/garoon2gs/internal/retry"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/sheets/v4"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SheetsQuota はGoogle Sheets APIの利用上限と再試行方針を表す構造体です
+type SheetsQuota struct {
+	ReadsPerMinute  int          // 1分あたりの読み取りリクエスト数の上限（0の場合は制限なし）
+	WritesPerMinute int          // 1分あたりの書き込みリクエスト数の上限（0の場合は制限なし）
+	Retry           retry.Policy // 429/503の場合の再試行方針
+}
+
+// DefaultSheetsQuota はデフォルトの利用上限を返します
+// Google Sheets APIのユーザーごとのデフォルトの上限（1分あたり読み取り60回・書き込み60回）に合わせています
+func DefaultSheetsQuota() SheetsQuota {
+	return SheetsQuota{
+		ReadsPerMinute:  60,
+		WritesPerMinute: 60,
+		Retry: retry.Policy{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     64 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		},
+	}
+}
+
+// LoadSheetsQuota は環境変数から利用上限と再試行方針（SHEETS_RETRY_*）を読み込みます
+// 設定されていない項目はデフォルト値を使用します
+func LoadSheetsQuota() (SheetsQuota, error) {
+	quota := DefaultSheetsQuota()
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"SHEETS_READS_PER_MINUTE", &quota.ReadsPerMinute},
+		{"SHEETS_WRITES_PER_MINUTE", &quota.WritesPerMinute},
+	}
+	for _, e := range ints {
+		v := os.Getenv(e.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return quota, fmt.Errorf("%sの値が不正です: %q", e.name, v)
+		}
+		*e.value = n
+	}
+
+	policy, err := retry.LoadPolicy("SHEETS_RETRY", quota.Retry)
+	if err != nil {
+		return quota, err
+	}
+	quota.Retry = policy
+
+	return quota, nil
+}
+
+// SheetsMetrics はGoogle Sheets APIの利用状況を表す構造体です
+type SheetsMetrics struct {
+	Reads        int           // 読み取りリクエスト数（再試行を含む）
+	Writes       int           // 書き込みリクエスト数（再試行を含む）
+	Retries      int           // 429/503による再試行の回数
+	Throttled    int           // 利用上限のために待機したリクエスト数
+	ThrottleWait time.Duration // 利用上限のために待機した時間の合計
+}
+
+// String はログ出力用の文字列を返します
+func (m SheetsMetrics) String() string {
+	return fmt.Sprintf("読み取り %d回、書き込み %d回、再試行 %d回、流量制限による待機 %d回（合計 %v）",
+		m.Reads, m.Writes, m.Retries, m.Throttled, m.ThrottleWait.Round(time.Millisecond))
+}
+
+// QuotaSheetsAPI は利用上限を超えないよう流量を制限し、429/503の場合に再試行する SheetsAPI です
+type QuotaSheetsAPI struct {
+	api    SheetsAPI
+	quota  SheetsQuota
+	reads  *ratelimit.Limiter
+	writes *ratelimit.Limiter
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu      sync.Mutex
+	metrics SheetsMetrics
+}
+
+// NewQuotaSheetsAPI は api を利用上限と再試行方針に従って呼び出す QuotaSheetsAPI を作成します
+func NewQuotaSheetsAPI(api SheetsAPI, quota SheetsQuota) *QuotaSheetsAPI {
+	return &QuotaSheetsAPI{
+		api:    api,
+		quota:  quota,
+		reads:  ratelimit.New(quota.ReadsPerMinute, time.Minute),
+		writes: ratelimit.New(quota.WritesPerMinute, time.Minute),
+		now:    time.Now,
+		sleep:  retry.Sleep,
+	}
+}
+
+// Metrics はこれまでの利用状況を返します
+func (q *QuotaSheetsAPI) Metrics() SheetsMetrics {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.metrics
+}
+
+func (q *QuotaSheetsAPI) GetValues(ctx context.Context, spreadsheetID, rng string) ([][]interface{}, error) {
+	var values [][]interface{}
+	err := q.do(ctx, false, "読み取り（"+rng+"）", func() error {
+		var err error
+		values, err = q.api.GetValues(ctx, spreadsheetID, rng)
+		return err
+	})
+	return values, err
+}
+
+func (q *QuotaSheetsAPI) BatchGetValues(ctx context.Context, spreadsheetID string, ranges []string) ([][][]interface{}, error) {
+	var values [][][]interface{}
+	err := q.do(ctx, false, fmt.Sprintf("一括読み取り（%d範囲）", len(ranges)), func() error {
+		var err error
+		values, err = q.api.BatchGetValues(ctx, spreadsheetID, ranges)
+		return err
+	})
+	return values, err
+}
+
+func (q *QuotaSheetsAPI) BatchUpdateValues(ctx context.Context, spreadsheetID string, data []*sheets.ValueRange) error {
+	return q.do(ctx, true, fmt.Sprintf("一括書き込み（%dセル）", len(data)), func() error {
+		return q.api.BatchUpdateValues(ctx, spreadsheetID, data)
+	})
+}
+
+func (q *QuotaSheetsAPI) ListSheets(ctx context.Context, spreadsheetID string) ([]SheetInfo, error) {
+	var infos []SheetInfo
+	err := q.do(ctx, false, "シート一覧の取得", func() error {
+		var err error
+		infos, err = q.api.ListSheets(ctx, spreadsheetID)
+		return err
+	})
+	return infos, err
+}
+
+func (q *QuotaSheetsAPI) DuplicateSheet(ctx context.Context, spreadsheetID string, sourceSheetID int64, title string, index int64) (SheetInfo, error) {
+	var info SheetInfo
+	err := q.do(ctx, true, "シートの複製（"+title+"）", func() error {
+		var err error
+		info, err = q.api.DuplicateSheet(ctx, spreadsheetID, sourceSheetID, title, index)
+		return err
+	})
+	return info, err
+}
+
+func (q *QuotaSheetsAPI) DeleteSheet(ctx context.Context, spreadsheetID string, sheetID int64) error {
+	return q.do(ctx, true, fmt.Sprintf("シートの削除（ID %d）", sheetID), func() error {
+		return q.api.DeleteSheet(ctx, spreadsheetID, sheetID)
+	})
+}
+
+// do は利用上限に達している場合は待機してから op を実行し、429/503の場合は再試行します
+// 再試行の待機時間はGaroon APIと同じ retry.Retrier で決め、Retry-Afterが返された場合はその値に従います
+func (q *QuotaSheetsAPI) do(ctx context.Context, write bool, desc string, op func() error) error {
+	limiter := q.reads
+	if write {
+		limiter = q.writes
+	}
+
+	r := retry.Retrier{
+		Policy:  q.quota.Retry,
+		Sleep:   q.sleep,
+		OnRetry: func() { q.record(func(m *SheetsMetrics) { m.Retries++ }) },
+	}
+
+	return r.Do(ctx, "Sheets APIの"+desc, func() error {
+		if wait := limiter.Reserve(q.now()); wait > 0 {
+			q.record(func(m *SheetsMetrics) {
+				m.Throttled++
+				m.ThrottleWait += wait
+			})
+			if err := q.sleep(ctx, wait); err != nil {
+				return fmt.Errorf("Sheets APIの流量制限の待機中に中断されました: %v", err)
+			}
+		}
+
+		q.record(func(m *SheetsMetrics) {
+			if write {
+				m.Writes++
+			} else {
+				m.Reads++
+			}
+		})
+
+		err := op()
+		if err != nil && isRetryableSheetsError(err) {
+			return &retry.Error{Err: err, RetryAfter: sheetsRetryAfter(err)}
+		}
+		return err
+	})
+}
+
+func (q *QuotaSheetsAPI) record(f func(m *SheetsMetrics)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f(&q.metrics)
+}
+
+// isRetryableSheetsError は429（利用上限超過）または503（一時的に利用不可）のエラーかどうかを判定します
+func isRetryableSheetsError(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	return gerr.Code == http.StatusTooManyRequests || gerr.Code == http.StatusServiceUnavailable
+}
+
+// sheetsRetryAfter はエラー応答のRetry-Afterヘッダーから待機時間を取得します（指定がない場合は0）
+func sheetsRetryAfter(err error) time.Duration {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return 0
+	}
+	return retry.ParseRetryAfter(gerr.Header.Get("Retry-After"), time.Now())
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"github.com/eotel/garoon2gs/internal/retry"
+	"google.golang.org/api/googleapi"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// flakySheetsAPI は最初の failures 回の呼び出しで err を返すテスト用の SheetsAPI です
+type flakySheetsAPI struct {
+	*fakeSheetsAPI
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakySheetsAPI) GetValues(ctx context.Context, spreadsheetID, rng string) ([][]interface{}, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return f.fakeSheetsAPI.GetValues(ctx, spreadsheetID, rng)
+}
+
+// newTestQuotaSheetsAPI は待機せずに待機時間を記録する QuotaSheetsAPI を作成します
+func newTestQuotaSheetsAPI(api SheetsAPI, quota SheetsQuota) (*QuotaSheetsAPI, *[]time.Duration) {
+	var waits []time.Duration
+	q := NewQuotaSheetsAPI(api, quota)
+	q.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return q, &waits
+}
+
+func TestQuotaSheetsAPIRetry(t *testing.T) {
+	tests := []struct {
+		name            string
+		failures        int
+		err             error
+		expectError     bool
+		expectedCalls   int
+		expectedRetries int
+		expectedWaits   []time.Duration
+	}{
+		{"429は再試行して成功", 2, &googleapi.Error{Code: http.StatusTooManyRequests, Message: "Quota exceeded for quota metric 'Read requests'"}, false, 3, 2, []time.Duration{time.Second, 2 * time.Second}},
+		{"503は再試行して成功", 1, &googleapi.Error{Code: http.StatusServiceUnavailable}, false, 2, 1, []time.Duration{time.Second}},
+		{"429が続く場合は最大試行回数で失敗", 10, &googleapi.Error{Code: http.StatusTooManyRequests}, true, 3, 2, []time.Duration{time.Second, 2 * time.Second}},
+		{"Retry-Afterつきの429はその時間だけ待機", 1, &googleapi.Error{Code: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"7"}}}, false, 2, 1, []time.Duration{7 * time.Second}},
+		{"400は再試行しない", 1, &googleapi.Error{Code: http.StatusBadRequest}, true, 1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSheetsAPI()
+			fake.addSheet("book", "シート1")
+			flaky := &flakySheetsAPI{fakeSheetsAPI: fake, failures: tt.failures, err: tt.err}
+
+			// ゆらぎなしの再試行方針で、待機時間を正確に確認する
+			policy := retry.Policy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Multiplier: 2}
+			q, waits := newTestQuotaSheetsAPI(flaky, SheetsQuota{Retry: policy})
+			_, err := q.GetValues(context.Background(), "book", "シート1!A1")
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if flaky.calls != tt.expectedCalls {
+				t.Errorf("expected %d calls but got %d", tt.expectedCalls, flaky.calls)
+			}
+
+			m := q.Metrics()
+			if m.Retries != tt.expectedRetries || m.Reads != tt.expectedCalls {
+				t.Errorf("unexpected metrics: %+v", m)
+			}
+			if !reflect.DeepEqual(*waits, tt.expectedWaits) {
+				t.Errorf("expected waits %v but got %v", tt.expectedWaits, *waits)
+			}
+		})
+	}
+}
+
+func TestQuotaSheetsAPIThrottle(t *testing.T) {
+	fake := newFakeSheetsAPI()
+	fake.addSheet("book", "シート1")
+
+	q, waits := newTestQuotaSheetsAPI(fake, SheetsQuota{ReadsPerMinute: 2, WritesPerMinute: 1, Retry: retry.Policy{MaxAttempts: 1}})
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := q.GetValues(ctx, "book", "シート1!A1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := q.BatchUpdateValues(ctx, "book", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 3回目の読み取りのみ1分待機し、書き込みは読み取りと別に数える
+	if len(*waits) != 1 || (*waits)[0] != time.Minute {
+		t.Errorf("expected a single 1m wait but got %v", *waits)
+	}
+
+	m := q.Metrics()
+	if m.Reads != 3 || m.Writes != 1 || m.Throttled != 1 || m.ThrottleWait != time.Minute {
+		t.Errorf("unexpected metrics: %+v", m)
+	}
+}
+
+func TestLoadSheetsQuota(t *testing.T) {
+	t.Setenv("SHEETS_READS_PER_MINUTE", "300")
+	t.Setenv("SHEETS_WRITES_PER_MINUTE", "")
+	t.Setenv("SHEETS_RETRY_MAX_ATTEMPTS", "")
+	t.Setenv("SHEETS_RETRY_INITIAL_BACKOFF", "")
+	t.Setenv("SHEETS_RETRY_MAX_BACKOFF", "2m")
+	t.Setenv("SHEETS_RETRY_JITTER", "")
+
+	quota, err := LoadSheetsQuota()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaults := DefaultSheetsQuota()
+	if quota.ReadsPerMinute != 300 || quota.WritesPerMinute != defaults.WritesPerMinute {
+		t.Errorf("unexpected quota: %+v", quota)
+	}
+	if quota.Retry.MaxBackoff != 2*time.Minute || quota.Retry.MaxAttempts != defaults.Retry.MaxAttempts || quota.Retry.Jitter != defaults.Retry.Jitter {
+		t.Errorf("unexpected retry policy: %+v", quota.Retry)
+	}
+
+	t.Setenv("SHEETS_RETRY_MAX_ATTEMPTS", "0")
+	if _, err := LoadSheetsQuota(); err == nil {
+		t.Error("expected error for SHEETS_RETRY_MAX_ATTEMPTS=0 but got none")
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/rules"
 	"google.golang.org/api/sheets/v4"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
@@ -20,6 +23,59 @@ type ScheduleWriter struct {
 	outingMenus  []string // 外出、出張などの特殊な出勤
 	normalPlace  string   // 通常の勤務地（"渋谷"）
 	nameCol      string
+	dateRows     map[int]int             // 日→行番号（loadDateRowsでDATE列から作成）
+	sheetMapper  *SheetMapper            // シート名から年月を取得するマッパー（nilの場合はloadLayoutで作成）
+	rules        *rules.RuleSet          // RULES_PATHで指定されたルール（未指定の場合はnil）
+	offDayLabel  string                  // 勤務しない曜日のラベル（OFF_DAY_LABEL）
+	profiles     map[string]*userProfile // ユーザーID → ユーザーごとの設定（設定のないユーザーは含まない）
+	profile      *userProfile            // w.name のユーザーの設定（nilの場合は共通の設定）
+	options      WriteOptions
+}
+
+// WriteOptions は書き込み時の動作を指定する構造体です
+type WriteOptions struct {
+	DryRun bool          // trueの場合は書き込まずに変更内容をReportに記録する
+	Report *ChangeReport // ドライランの変更内容の記録先
+	State  *WriteState   // 最後に書き込んだ値の記録（nilの場合は手動編集を検出しない）
+	Force  bool          // trueの場合は手動で編集されたセルも上書きする
+
+	PastDates PastDatePolicy // 今日より前の日付の扱い（空の場合は PastDateSkip）
+	Today     time.Time      // 過去の日付の判定基準（ゼロ値の場合は実行時の日付）
+	From      time.Time      // 書き込み対象期間の開始（ゼロ値の場合は制限なし）
+	To        time.Time      // 書き込み対象期間の終了（ゼロ値の場合は制限なし）
+}
+
+// PastDatePolicy は今日より前の日付のセルをどう扱うかを表します
+type PastDatePolicy string
+
+const (
+	PastDateSkip         PastDatePolicy = "skip"          // 過去の日付は書き込まない
+	PastDateCurrentMonth PastDatePolicy = "current-month" // 今月の過去の日付は書き込む
+	PastDateAll          PastDatePolicy = "all"           // 期間内のすべての過去の日付を書き込む
+	PastDateEmptyOnly    PastDatePolicy = "empty-only"    // 過去の日付は空のセルのみ書き込む
+)
+
+// ParsePastDatePolicy は文字列から PastDatePolicy を取得します（空文字は PastDateSkip）
+func ParsePastDatePolicy(s string) (PastDatePolicy, error) {
+	switch p := PastDatePolicy(s); p {
+	case "":
+		return PastDateSkip, nil
+	case PastDateSkip, PastDateCurrentMonth, PastDateAll, PastDateEmptyOnly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown past date policy %q (expected skip, current-month, all or empty-only)", s)
+	}
+}
+
+// cellUpdate は1つのセルに書き込む内容を表す構造体です
+type cellUpdate struct {
+	date        time.Time
+	user        string // ヘッダー行のユーザー名
+	row         int    // 行番号（1始まり）
+	col         int    // 列番号（0始まり）
+	rng         string // 書き込みの記録・変更一覧に使うセル範囲（"シート名!J8"）
+	value       string
+	onlyIfEmpty bool // 空のセルの場合のみ書き込む
 }
 
 // NewScheduleWriter は新しい ScheduleWriter インスタンスを作成します
@@ -65,16 +121,42 @@ func NewScheduleWriter() (*ScheduleWriter, error) {
 		normalPlace = "渋谷" // デフォルト値
 	}
 
+	// ルールファイルの読み込み（未指定の場合はHOLIDAY_MENUS/OUTING_MENUSから判定）
+	var ruleSet *rules.RuleSet
+	if rulesPath := os.Getenv("RULES_PATH"); rulesPath != "" {
+		configDir, err := client.GetConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config directory: %v", err)
+		}
+		ruleSet, err = rules.Load(resolveConfigPath(configDir, rulesPath))
+		if err != nil {
+			return nil, err
+		}
+		if ruleSet.Default == "" {
+			ruleSet.Default = normalPlace
+		}
+	}
+
 	return &ScheduleWriter{
 		headerRow:    headerRow,
 		dateCol:      dateCol,
-		name:         "", // SaveToSheet()で設定されるため空文字で初期化
+		name:         "", // SheetPlanner.Add()で設定されるため空文字で初期化
 		holidayMenus: holidayMenus,
 		outingMenus:  outingMenus,
 		normalPlace:  normalPlace,
+		rules:        ruleSet,
+		offDayLabel:  loadOffDayLabel(),
 	}, nil
 }
 
+// resolveConfigPath は相対パスを設定ディレクトリからのパスとして解決します
+func resolveConfigPath(configDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configDir, path)
+}
+
 // findNameColumn はヘッダー行から名前の列を特定します
 func (w *ScheduleWriter) findNameColumn(headerValues []interface{}) (string, error) {
 	log.Printf("Searching for name '%s' in header values: %v", w.name, headerValues)
@@ -91,48 +173,24 @@ func (w *ScheduleWriter) findNameColumn(headerValues []interface{}) (string, err
 	return "", fmt.Errorf("column for name %q not found in header row", w.name)
 }
 
-// getLastDateRow はDATE列の最後の日付を探して、最終行を特定します
-func (w *ScheduleWriter) getLastDateRow(srv *sheets.Service, spreadsheetID, sheetName string) (int, error) {
-	dateRange := fmt.Sprintf("%s!%s%d:%s%d", sheetName, w.dateCol, w.headerRow+1, w.dateCol, 100)
+// loadDateRows はDATE列を読み取り、日→行番号の対応表を作成します
+func (w *ScheduleWriter) loadDateRows(ctx context.Context, api SheetsAPI, spreadsheetID, sheetName string, month time.Time) error {
+	// ヘッダー行の次の行から列の最後までを読み取る
+	dateRange := sheetRange(sheetName, fmt.Sprintf("%s%d:%s", w.dateCol, w.headerRow+1, w.dateCol))
 	log.Printf("Reading date column range: %s", dateRange)
 
-	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, dateRange).Do()
+	values, err := api.GetValues(ctx, spreadsheetID, dateRange)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read date column: %v", err)
-	}
-
-	log.Printf("Date column values: %+v", resp.Values)
-
-	if len(resp.Values) == 0 {
-		return 0, fmt.Errorf("no data found in date column")
+		return fmt.Errorf("failed to read date column: %v", err)
 	}
 
-	// 数値が入っている最後の行を探す
-	lastRow := w.headerRow
-	for i, row := range resp.Values {
-		if len(row) == 0 {
-			continue
-		}
-
-		// 数値として解釈できる値のみを考慮
-		switch v := row[0].(type) {
-		case float64:
-			if v > 0 {
-				lastRow = w.headerRow + i + 1
-			}
-		case int:
-			if v > 0 {
-				lastRow = w.headerRow + i + 1
-			}
-		case string:
-			if num, err := strconv.Atoi(v); err == nil && num > 0 {
-				lastRow = w.headerRow + i + 1
-			}
-		}
+	w.dateRows = buildDateRowIndex(values, w.headerRow+1, month)
+	if len(w.dateRows) == 0 {
+		return fmt.Errorf("no dates for %s found in date column", month.Format("2006-01"))
 	}
 
-	log.Printf("Found last date row at: %d", lastRow)
-	return lastRow, nil
+	log.Printf("Found %d date rows in sheet %s", len(w.dateRows), sheetName)
+	return nil
 }
 
 // getCellPosition は指定された日付に対応するセルの位置を返します
@@ -141,40 +199,59 @@ func (w *ScheduleWriter) getCellPosition(date time.Time) (row int, col string, e
 		return 0, "", fmt.Errorf("name column is not initialized")
 	}
 
-	// 日付から行番号を計算
+	// DATE列を読み取り済みの場合は対応表から行番号を取得
+	if w.dateRows != nil {
+		row, ok := w.dateRows[date.Day()]
+		if !ok {
+			return 0, "", fmt.Errorf("row for %s not found in date column", date.Format("2006-01-02"))
+		}
+		return row, w.nameCol, nil
+	}
+
+	// 対応表がない場合は日付から行番号を計算
 	day := date.Day()
 	row = w.headerRow + day
 
 	return row, w.nameCol, nil
 }
 
-// determineEventStatus はイベントの状態を判定します
-func (w *ScheduleWriter) determineEventStatus(events []client.Event) string {
-	if len(events) == 0 {
-		// 予定がない場合は通常の勤務地を返す
-		return "渋谷"
+// determineEventStatus は指定日のイベントの状態を判定します
+func (w *ScheduleWriter) determineEventStatus(date time.Time, events []client.Event) string {
+	return w.ruleSet(date).Evaluate(date, events)
+}
+
+// ruleSet は指定日の判定に使用するルールを返します
+// ユーザーのルールファイル、RULES_PATH のルールの順に使用し、どちらもない場合は休暇・外出メニューと通常の勤務地から作成します
+// ユーザーの勤務地が指定されている場合は予定のない日のラベルを勤務地に、勤務しない曜日は OFF_DAY_LABEL にします
+func (w *ScheduleWriter) ruleSet(date time.Time) *rules.RuleSet {
+	place := w.normalPlace
+	if w.profile != nil && w.profile.place != "" {
+		place = w.profile.place
 	}
 
-	// 1. 休み判定が一つでもあるかチェック
-	for _, event := range events {
-		for _, holiday := range w.holidayMenus {
-			if event.EventMenu == holiday {
-				return "週休" // 休み判定があれば必ず"週休"を返す
-			}
-		}
+	rs := w.rules
+	if w.profile != nil && w.profile.rules != nil {
+		rs = w.profile.rules
+	}
+	if rs == nil {
+		rs = rules.FromMenus(w.holidayMenus, w.outingMenus, place)
 	}
 
-	// 2. OUTING_MENUSに該当するものがあるかチェック
-	for _, event := range events {
-		for _, outingMenu := range w.outingMenus {
-			if event.EventMenu == outingMenu {
-				return "外出"
-			}
-		}
+	label := rs.Default
+	if label == "" || (w.profile != nil && w.profile.place != "") {
+		label = place
+	}
+	if !w.profile.worksOn(date.Weekday()) {
+		label = w.offDayLabel
+	}
+	if label == rs.Default {
+		return rs
 	}
 
-	// 3. それ以外の場合は "渋谷" を返す
-	return "渋谷"
+	// 共有しているルールを変更しないよう、コピーのラベルを変更する
+	custom := *rs
+	custom.Default = label
+	return &custom
 }
 
 // columnIndexToName は0-based indexをA1記法の列名に変換します
@@ -190,132 +267,295 @@ func columnIndexToName(index int) string {
 	return name
 }
 
-// WriteSchedule は指定されたシートにスケジュールを書き込みます
-func (w *ScheduleWriter) WriteSchedule(srv *sheets.Service, spreadsheetID, sheetName string, monthlyEvents map[int][]client.Event) error {
-	// まずヘッダー行から名前の列を特定
-	headerRange := fmt.Sprintf("%s!%d:%d", sheetName, w.headerRow, w.headerRow)
+// columnNameToIndex はA1記法の列名を0-based indexに変換します
+func columnNameToIndex(name string) int {
+	index := 0
+	for _, ch := range name {
+		index = index*26 + int(ch-'A'+1)
+	}
+	return index - 1
+}
+
+// sheetLayout はシートのヘッダー行とDATE列から読み取った、セルの位置の情報です
+type sheetLayout struct {
+	name     string
+	month    time.Time     // シートの年月
+	header   []interface{} // ヘッダー行の値
+	dateRows map[int]int   // 日→行番号
+}
+
+// loadLayout はシートのヘッダー行とDATE列を読み取ります
+func (w *ScheduleWriter) loadLayout(ctx context.Context, api SheetsAPI, spreadsheetID, sheetName string) (*sheetLayout, error) {
+	// ヘッダー行を読み取る（名前の列の特定に使用）
+	headerRange := sheetRange(sheetName, fmt.Sprintf("%d:%d", w.headerRow, w.headerRow))
 	log.Printf("Reading header row from range: %s", headerRange)
 
-	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, headerRange).Do()
+	headerValues, err := api.GetValues(ctx, spreadsheetID, headerRange)
 	if err != nil {
-		return fmt.Errorf("failed to read header row: %v", err)
+		return nil, fmt.Errorf("failed to read header row: %v", err)
 	}
 
-	if len(resp.Values) == 0 || len(resp.Values[0]) == 0 {
-		return fmt.Errorf("header row is empty")
+	if len(headerValues) == 0 || len(headerValues[0]) == 0 {
+		return nil, fmt.Errorf("header row is empty")
 	}
 
-	// 名前の列を特定
-	w.nameCol, err = w.findNameColumn(resp.Values[0])
-	if err != nil {
-		return fmt.Errorf("failed to find name column: %v", err)
+	// シートマッパーを取得して、シート名から年月を取得
+	sheetMapper := w.sheetMapper
+	if sheetMapper == nil {
+		if sheetMapper, err = NewSheetMapper(); err != nil {
+			return nil, fmt.Errorf("failed to create sheet mapper: %v", err)
+		}
 	}
 
-	log.Printf("Found name column: %s", w.nameCol)
-
-	// 日付列の内容を取得
-	lastRow, err := w.getLastDateRow(srv, spreadsheetID, sheetName)
-	if err != nil {
-		return fmt.Errorf("failed to determine last date row: %v", err)
+	sheetMonth := sheetMapper.GetMonthFromSheetName(sheetName)
+	if sheetMonth == nil {
+		return nil, fmt.Errorf("failed to determine month for sheet: %s", sheetName)
 	}
 
-	// 日付列の範囲を読み取り
-	dateRange := fmt.Sprintf("%s!%s%d:%s%d", sheetName, w.dateCol, w.headerRow+1, w.dateCol, lastRow)
-	dateResp, err := srv.Spreadsheets.Values.Get(spreadsheetID, dateRange).Do()
-	if err != nil {
-		return fmt.Errorf("failed to read date column: %v", err)
+	log.Printf("Sheet %s corresponds to month: %s", sheetName, sheetMonth.Format("2006-01"))
+
+	// 日付列から日→行番号の対応表を作成
+	if err := w.loadDateRows(ctx, api, spreadsheetID, sheetName, *sheetMonth); err != nil {
+		return nil, err
 	}
 
-	// 現在の日付を取得
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return &sheetLayout{
+		name:     sheetName,
+		month:    *sheetMonth,
+		header:   headerValues[0],
+		dateRows: w.dateRows,
+	}, nil
+}
 
-	// シートマッパーを取得して、シート名から年月を取得
-	sheetMapper, err := NewSheetMapper()
+// planCells は w.name のユーザーについて、シートの各日に書き込む内容を求めます
+// 名前の列と日→行番号の対応表は layout から設定します
+func (w *ScheduleWriter) planCells(layout *sheetLayout, monthlyEvents map[int][]client.Event) ([]cellUpdate, error) {
+	// ヘッダー行から名前の列を特定
+	var err error
+	w.nameCol, err = w.findNameColumn(layout.header)
 	if err != nil {
-		return fmt.Errorf("failed to create sheet mapper: %v", err)
+		return nil, fmt.Errorf("failed to find name column: %v", err)
 	}
+	w.dateRows = layout.dateRows
 
-	sheetMonth := sheetMapper.GetMonthFromSheetName(sheetName)
-	if sheetMonth == nil {
-		return fmt.Errorf("failed to determine month for sheet: %s", sheetName)
-	}
+	log.Printf("Found name column: %s", w.nameCol)
 
-	log.Printf("Sheet %s corresponds to month: %s", sheetName, sheetMonth.Format("2006-01"))
+	// 過去の日付の判定基準となる今日の日付
+	today := w.options.Today
+	if today.IsZero() {
+		today = time.Now()
+	}
+	today = startOfDay(today)
 
 	// 更新内容を準備
-	var updates []*sheets.ValueRange
+	var updates []cellUpdate
 
 	// 各日付に対して処理
-	for i, row := range dateResp.Values {
-		if len(row) == 0 {
+	sheetMonth := layout.month
+	for day := 1; day <= daysIn(sheetMonth.Year(), sheetMonth.Month()); day++ {
+		// このシートのこの日の日付を計算
+		cellDate := time.Date(sheetMonth.Year(), sheetMonth.Month(), day, 0, 0, 0, 0, time.Local)
+
+		// 取得期間外の日付は予定が不明なためスキップ
+		if !w.inRange(cellDate) {
 			continue
 		}
 
-		// 日付が数値として存在するかチェック
-		var day int
-		switch v := row[0].(type) {
-		case float64:
-			day = int(v)
-		case int:
-			day = v
-		case string:
-			if num, err := strconv.Atoi(v); err == nil {
-				day = num
-			}
-		default:
+		// 過去の日付は設定に従ってスキップ
+		write, onlyIfEmpty := w.pastDateAction(cellDate, today)
+		if !write {
+			log.Printf("Skipping past date: %s (before today: %s, policy: %s)", cellDate.Format("2006-01-02"), today.Format("2006-01-02"), w.options.PastDates)
 			continue
 		}
 
-		if day <= 0 {
+		// 該当行の位置を取得（DATE列にない日はスキップ）
+		rowNum, col, err := w.getCellPosition(cellDate)
+		if err != nil {
+			log.Printf("Skipping %s: %v", cellDate.Format("2006-01-02"), err)
 			continue
 		}
 
-		// このシートのこの日の日付を計算
-		cellDate := time.Date(sheetMonth.Year(), sheetMonth.Month(), day, 0, 0, 0, 0, time.Local)
+		// イベントの状態を判定（イベントがない日はデフォルトのラベル）
+		status := w.determineEventStatus(cellDate, monthlyEvents[day])
+
+		// 更新を追加
+		updates = append(updates, cellUpdate{
+			date:        cellDate,
+			user:        w.name,
+			row:         rowNum,
+			col:         columnNameToIndex(col),
+			rng:         fmt.Sprintf("%s!%s%d", layout.name, col, rowNum),
+			value:       status,
+			onlyIfEmpty: onlyIfEmpty,
+		})
+	}
+
+	return updates, nil
+}
+
+// pastDateAction は過去の日付の扱いに従って、セルを書き込むかどうかと
+// 空のセルの場合のみ書き込むかどうかを返します（今日以降の日付は常に書き込む）
+func (w *ScheduleWriter) pastDateAction(date, today time.Time) (write bool, onlyIfEmpty bool) {
+	if !date.Before(today) {
+		return true, false
+	}
+
+	switch w.options.PastDates {
+	case PastDateAll:
+		return true, false
+	case PastDateCurrentMonth:
+		return date.Year() == today.Year() && date.Month() == today.Month(), false
+	case PastDateEmptyOnly:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// inRange は日付が書き込み対象期間に含まれるかどうかを判定します
+func (w *ScheduleWriter) inRange(date time.Time) bool {
+	if !w.options.From.IsZero() && date.Before(startOfDay(w.options.From)) {
+		return false
+	}
+	if !w.options.To.IsZero() && date.After(w.options.To) {
+		return false
+	}
+	return true
+}
+
+// startOfDay は指定された日時と同じ日の0時（ローカルタイム）を返します
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// maxValueRangesPerBatch は1回のバッチ更新で書き込むセルの最大数です
+// リクエストのサイズがAPIの上限を超えないよう、これを超える場合は分割して書き込みます
+const maxValueRangesPerBatch = 1000
+
+// applyUpdates は現在の値と異なるセルのみを書き込みます
+// 手動で編集されたセルは Force が指定されていない限りスキップし、
+// ドライランの場合は書き込まずに変更内容をレポートに記録します
+func (w *ScheduleWriter) applyUpdates(ctx context.Context, api SheetsAPI, spreadsheetID, sheetName string, updates []cellUpdate) error {
+	if len(updates) == 0 {
+		log.Printf("No updates to write for sheet %s (all dates are in the past)", sheetName)
+		return nil
+	}
+
+	current, err := readCurrentValues(ctx, api, spreadsheetID, sheetName, updates)
+	if err != nil {
+		return err
+	}
 
-		// 過去の日付はスキップ
-		if cellDate.Before(today) {
-			log.Printf("Skipping past date: %s (before today: %s)", cellDate.Format("2006-01-02"), today.Format("2006-01-02"))
+	state := w.options.State
+	var pending []cellUpdate
+	for _, u := range updates {
+		old := current[u.rng]
+		if u.onlyIfEmpty && old != "" {
+			continue
+		}
+		if old == u.value {
+			// 既に同じ値のセルは書き込まず、記録だけ更新する
+			if state != nil && !w.options.DryRun {
+				state.Record(spreadsheetID, u.rng, u.value)
+			}
 			continue
 		}
 
-		// 該当行の行番号を計算
-		rowNum := w.headerRow + i + 1
+		manualEdit := !w.options.Force && state != nil && state.IsManualEdit(spreadsheetID, u.rng, old)
+
+		if w.options.DryRun {
+			if w.options.Report != nil {
+				w.options.Report.Add(CellChange{
+					Spreadsheet: spreadsheetID,
+					Sheet:       sheetName,
+					User:        u.user,
+					Date:        u.date.Format("2006-01-02"),
+					Range:       u.rng,
+					Old:         old,
+					New:         u.value,
+					ManualEdit:  manualEdit,
+				})
+			}
+			continue
+		}
 
-		// イベントの状態を判定
-		var status string
-		if events, exists := monthlyEvents[day]; exists {
-			status = w.determineEventStatus(events)
-		} else {
-			// イベントがない日は通常勤務（渋谷）
-			status = w.normalPlace
+		if manualEdit {
+			last, _ := state.LastWritten(spreadsheetID, u.rng)
+			log.Printf("警告: 手動で編集されたセルをスキップします: %s（現在の値: %q、前回書き込んだ値: %q、新しい値: %q）", u.rng, old, last, u.value)
+			continue
 		}
 
-		// 更新を追加
-		updateRange := fmt.Sprintf("%s!%s%d", sheetName, w.nameCol, rowNum)
-		updates = append(updates, &sheets.ValueRange{
-			Range:  updateRange,
-			Values: [][]interface{}{{status}},
-		})
+		pending = append(pending, u)
+	}
+
+	if w.options.DryRun {
+		log.Printf("Dry run: checked %d cells in sheet %s", len(updates), sheetName)
+		return nil
 	}
 
-	if len(updates) > 0 {
-		log.Printf("Attempting to write %d updates to sheet %s", len(updates), sheetName)
+	if len(pending) == 0 {
+		log.Printf("No changes to write for sheet %s", sheetName)
+		return nil
+	}
+
+	log.Printf("Attempting to write %d updates to sheet %s", len(pending), sheetName)
 
-		// バッチ更新を実行（OVERWRITE指定で既存の値を上書き）
-		req := &sheets.BatchUpdateValuesRequest{
-			ValueInputOption: "RAW",
-			Data:             updates,
+	for start := 0; start < len(pending); start += maxValueRangesPerBatch {
+		chunk := pending[start:min(start+maxValueRangesPerBatch, len(pending))]
+
+		data := make([]*sheets.ValueRange, 0, len(chunk))
+		for _, u := range chunk {
+			data = append(data, &sheets.ValueRange{
+				Range:  sheetRange(sheetName, fmt.Sprintf("%s%d", columnIndexToName(u.col), u.row)),
+				Values: [][]interface{}{{u.value}},
+			})
 		}
-		_, err = srv.Spreadsheets.Values.BatchUpdate(spreadsheetID, req).Do()
-		if err != nil {
+
+		// バッチ更新を実行（RAW指定で既存の値を上書き）
+		if err := api.BatchUpdateValues(ctx, spreadsheetID, data); err != nil {
 			return fmt.Errorf("failed to update values: %v", err)
 		}
-		log.Printf("Successfully wrote updates to sheet %s", sheetName)
-	} else {
-		log.Printf("No updates to write for sheet %s (all dates are in the past)", sheetName)
+
+		// 書き込みが成功した分だけ記録する
+		if state != nil {
+			for _, u := range chunk {
+				state.Record(spreadsheetID, u.rng, u.value)
+			}
+		}
 	}
+	log.Printf("Successfully wrote updates to sheet %s", sheetName)
 
 	return nil
 }
+
+// readCurrentValues は書き込み対象のセルの現在の値を読み取ります
+// 対象のセルをすべて含む範囲を1回で読み取り、セル範囲→値の対応表を返します
+func readCurrentValues(ctx context.Context, api SheetsAPI, spreadsheetID, sheetName string, updates []cellUpdate) (map[string]string, error) {
+	values := make(map[string]string, len(updates))
+	if len(updates) == 0 {
+		return values, nil
+	}
+
+	minRow, maxRow := updates[0].row, updates[0].row
+	minCol, maxCol := updates[0].col, updates[0].col
+	for _, u := range updates[1:] {
+		minRow, maxRow = min(minRow, u.row), max(maxRow, u.row)
+		minCol, maxCol = min(minCol, u.col), max(maxCol, u.col)
+	}
+
+	rng := sheetRange(sheetName, fmt.Sprintf("%s%d:%s%d", columnIndexToName(minCol), minRow, columnIndexToName(maxCol), maxRow))
+	rows, err := api.GetValues(ctx, spreadsheetID, rng)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current values: %v", err)
+	}
+
+	// レスポンスでは末尾の空の行・列が省略される
+	for _, u := range updates {
+		values[u.rng] = ""
+		r, c := u.row-minRow, u.col-minCol
+		if r < len(rows) && c < len(rows[r]) {
+			values[u.rng] = fmt.Sprint(rows[r][c])
+		}
+	}
+	return values, nil
+}
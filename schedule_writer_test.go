@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"github.com/eotel/garoon2gs/internal/client"
 	"os"
+	"sort"
 	"testing"
 	"time"
 )
@@ -167,7 +169,7 @@ func TestDetermineEventStatus(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := writer.determineEventStatus(tt.events)
+			result := writer.determineEventStatus(time.Date(2025, 2, 3, 0, 0, 0, 0, time.Local), tt.events)
 			if result != tt.expectedValue {
 				t.Errorf("expected %s but got %s", tt.expectedValue, result)
 			}
@@ -234,3 +236,261 @@ func TestFindNameColumn(t *testing.T) {
 		})
 	}
 }
+
+func TestPastDateAction(t *testing.T) {
+	today := time.Date(2025, 5, 14, 0, 0, 0, 0, time.Local)
+	lastMonth := time.Date(2025, 4, 30, 0, 0, 0, 0, time.Local)
+	earlierThisMonth := time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)
+	future := time.Date(2025, 5, 20, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name                string
+		policy              PastDatePolicy
+		date                time.Time
+		expectedWrite       bool
+		expectedOnlyIfEmpty bool
+	}{
+		{"未来の日付は常に書き込む", PastDateSkip, future, true, false},
+		{"当日は書き込む", PastDateSkip, today, true, false},
+		{"skip: 今月の過去", PastDateSkip, earlierThisMonth, false, false},
+		{"未指定はskip", "", earlierThisMonth, false, false},
+		{"current-month: 今月の過去", PastDateCurrentMonth, earlierThisMonth, true, false},
+		{"current-month: 先月", PastDateCurrentMonth, lastMonth, false, false},
+		{"all: 先月", PastDateAll, lastMonth, true, false},
+		{"empty-only: 先月", PastDateEmptyOnly, lastMonth, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &ScheduleWriter{options: WriteOptions{PastDates: tt.policy}}
+			write, onlyIfEmpty := writer.pastDateAction(tt.date, today)
+			if write != tt.expectedWrite || onlyIfEmpty != tt.expectedOnlyIfEmpty {
+				t.Errorf("expected (%v, %v) but got (%v, %v)", tt.expectedWrite, tt.expectedOnlyIfEmpty, write, onlyIfEmpty)
+			}
+		})
+	}
+}
+
+func TestParsePastDatePolicy(t *testing.T) {
+	if p, err := ParsePastDatePolicy(""); err != nil || p != PastDateSkip {
+		t.Errorf("expected skip for empty string but got %q (err: %v)", p, err)
+	}
+	if p, err := ParsePastDatePolicy("current-month"); err != nil || p != PastDateCurrentMonth {
+		t.Errorf("expected current-month but got %q (err: %v)", p, err)
+	}
+	if _, err := ParsePastDatePolicy("never"); err == nil {
+		t.Error("expected error for unknown policy but got none")
+	}
+}
+
+// newFebruaryWriter は2025年2月のシート（ヘッダー7行目、"三浦", "伊藤"の列）に書き込む
+// ScheduleWriter とフェイクのスプレッドシートを作成します
+func newFebruaryWriter(t *testing.T, opts WriteOptions) (*ScheduleWriter, *fakeSheetsAPI) {
+	cleanup := setupWriterTest(t)
+	t.Cleanup(cleanup)
+
+	writer, err := NewScheduleWriter()
+	if err != nil {
+		t.Fatalf("Failed to create ScheduleWriter: %v", err)
+	}
+	writer.name = "伊藤"
+	writer.holidayMenus = []string{"有休"}
+	writer.outingMenus = []string{"外出"}
+	writer.sheetMapper = &SheetMapper{
+		mappings: []SheetMapping{
+			{Month: time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), SheetName: "R6年度_2月"},
+		},
+	}
+	writer.options = opts
+
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book", "R6年度_2月", 7, time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), "三浦", "伊藤")
+	return writer, fake
+}
+
+// writeFebruary は newFebruaryWriter の writer のユーザー（ユーザーID "1"）の予定を SheetPlanner で書き込みます
+func writeFebruary(writer *ScheduleWriter, fake *fakeSheetsAPI, events map[int][]client.Event) error {
+	days := make([]int, 0, len(events))
+	for day := range events {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+
+	var all []client.Event
+	for _, day := range days {
+		all = append(all, events[day]...)
+	}
+
+	ctx := context.Background()
+	planner := newSheetPlanner(fake, "book", writer)
+	if err := planner.Add(ctx, "1", writer.name, all); err != nil {
+		return err
+	}
+	return planner.Flush(ctx)["1"]
+}
+
+// februaryEvents は2025年2月のテスト用のイベントを日ごとに返します
+func februaryEvents() map[int][]client.Event {
+	at := func(day, hour int) client.EventDateTime {
+		return client.EventDateTime{DateTime: time.Date(2025, 2, day, hour, 0, 0, 0, time.Local).Format(time.RFC3339)}
+	}
+	return map[int][]client.Event{
+		10: {{EventMenu: "有休", Start: at(10, 0), End: at(11, 0), IsAllDay: true}},
+		12: {{EventMenu: "外出", Start: at(12, 13), End: at(12, 15)}},
+		14: {{EventMenu: "有休", Start: at(14, 9), End: at(14, 12)}},
+	}
+}
+
+func TestScheduleWriterFullMonth(t *testing.T) {
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+
+	err := writeFebruary(writer, fake, februaryEvents())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"R6年度_2月!D8":  "渋谷",
+		"R6年度_2月!D17": "週休",
+		"R6年度_2月!D19": "外出",
+		"R6年度_2月!D21": "午前休/渋谷",
+		"R6年度_2月!D35": "渋谷",
+		"R6年度_2月!C8":  "", // 他のユーザーの列は変更しない
+		"R6年度_2月!D36": "", // 2月は28日まで
+	}
+	for cell, want := range expected {
+		if got := fake.get("book", cell); got != want {
+			t.Errorf("%s: expected %q but got %q", cell, want, got)
+		}
+	}
+	if fake.batchUpdates != 1 {
+		t.Errorf("expected 1 batch update but got %d", fake.batchUpdates)
+	}
+
+	// 2回目の書き込みでは変更がないため書き込まない
+	if err := writeFebruary(writer, fake, februaryEvents()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.batchUpdates != 1 {
+		t.Errorf("expected no additional batch update but got %d in total", fake.batchUpdates)
+	}
+}
+
+func TestScheduleWriterSkipsManualEdits(t *testing.T) {
+	state, err := LoadWriteState(t.TempDir() + "/state.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		State:     state,
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+
+	if err := writeFebruary(writer, fake, februaryEvents()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 書き込み後に手動で編集し、予定を変更して再度書き込む
+	fake.set("book", "R6年度_2月!D8", "在宅")
+	events := februaryEvents()
+	events[1] = []client.Event{{
+		EventMenu: "外出",
+		Start:     client.EventDateTime{DateTime: time.Date(2025, 2, 1, 13, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		End:       client.EventDateTime{DateTime: time.Date(2025, 2, 1, 15, 0, 0, 0, time.Local).Format(time.RFC3339)},
+	}}
+	if err := writeFebruary(writer, fake, events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fake.get("book", "R6年度_2月!D8"); got != "在宅" {
+		t.Errorf("expected manually edited cell to be kept but got %q", got)
+	}
+
+	writer.options.Force = true
+	if err := writeFebruary(writer, fake, events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fake.get("book", "R6年度_2月!D8"); got != "外出" {
+		t.Errorf("expected manually edited cell to be overwritten with --force but got %q", got)
+	}
+}
+
+func TestScheduleWriterDryRun(t *testing.T) {
+	report := &ChangeReport{}
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		DryRun:    true,
+		Report:    report,
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	fake.set("book", "R6年度_2月!D8", "渋谷")
+
+	if err := writeFebruary(writer, fake, februaryEvents()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fake.batchUpdates != 0 {
+		t.Errorf("expected no writes in dry run but got %d batch updates", fake.batchUpdates)
+	}
+	// 既に同じ値の2月1日を除く27日分
+	if changes := report.Changes(); len(changes) != 27 {
+		t.Errorf("expected 27 changes but got %d", len(changes))
+	}
+}
+
+func TestScheduleWriterPastDatePolicy(t *testing.T) {
+	today := time.Date(2025, 2, 15, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		policy   PastDatePolicy
+		expected map[string]string
+	}{
+		{
+			name:     "skip",
+			policy:   PastDateSkip,
+			expected: map[string]string{"R6年度_2月!D17": "", "R6年度_2月!D19": "在宅", "R6年度_2月!D22": "渋谷"},
+		},
+		{
+			name:     "empty-only",
+			policy:   PastDateEmptyOnly,
+			expected: map[string]string{"R6年度_2月!D17": "週休", "R6年度_2月!D19": "在宅", "R6年度_2月!D22": "渋谷"},
+		},
+		{
+			name:     "all",
+			policy:   PastDateAll,
+			expected: map[string]string{"R6年度_2月!D17": "週休", "R6年度_2月!D19": "外出", "R6年度_2月!D22": "渋谷"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, fake := newFebruaryWriter(t, WriteOptions{PastDates: tt.policy, Today: today})
+			fake.set("book", "R6年度_2月!D19", "在宅")
+
+			if err := writeFebruary(writer, fake, februaryEvents()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for cell, want := range tt.expected {
+				if got := fake.get("book", cell); got != want {
+					t.Errorf("%s: expected %q but got %q", cell, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleWriterNameNotFound(t *testing.T) {
+	writer, fake := newFebruaryWriter(t, WriteOptions{})
+	writer.name = "佐藤"
+
+	if err := writeFebruary(writer, fake, februaryEvents()); err == nil {
+		t.Error("expected error for unknown name but got none")
+	}
+	if fake.batchUpdates != 0 {
+		t.Errorf("expected no writes but got %d batch updates", fake.batchUpdates)
+	}
+}
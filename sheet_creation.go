@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/sheets/v4"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sheetCreation は見つからない月のシートをテンプレートのシートから作成するための設定です
+type sheetCreation struct {
+	templateTab string // 複製するテンプレートのシート名（SHEET_TEMPLATE_TAB）
+	weekdayCol  string // 曜日の列（WEEKDAY_COL、未指定の場合はDATE列の次の列）
+}
+
+// columnPattern はA1記法の列名（"A", "AB" など）です
+var columnPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// weekdayNames は曜日の列に書き込む曜日の表記です
+var weekdayNames = [7]string{"日", "月", "火", "水", "木", "金", "土"}
+
+// maxDaysInMonth は作成したシートに日付を書き込む行数です（短い月の残りの行は空にします）
+const maxDaysInMonth = 31
+
+// loadSheetCreation は環境変数からシートの作成の設定を読み込みます
+// SHEET_TEMPLATE_TAB が未指定の場合はnilを返します
+func loadSheetCreation(dateCol string) (*sheetCreation, error) {
+	templateTab := os.Getenv("SHEET_TEMPLATE_TAB")
+	if templateTab == "" {
+		return nil, nil
+	}
+
+	weekdayCol := strings.TrimSpace(os.Getenv("WEEKDAY_COL"))
+	if weekdayCol == "" {
+		weekdayCol = columnIndexToName(columnNameToIndex(dateCol) + 1)
+	} else if !columnPattern.MatchString(weekdayCol) {
+		return nil, fmt.Errorf("invalid WEEKDAY_COL %q (expected a column like B)", weekdayCol)
+	}
+
+	return &sheetCreation{templateTab: templateTab, weekdayCol: weekdayCol}, nil
+}
+
+// monthsBetween は from の月から to の月までの各月の初日を返します
+func monthsBetween(from, to time.Time) []time.Time {
+	var months []time.Time
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local); !month.After(to); month = month.AddDate(0, 1, 0) {
+		months = append(months, month)
+	}
+	return months
+}
+
+// newSheetName は月のシートを作成する際のシート名を返します（CSVに記載があればCSV、なければテンプレートから求めます）
+func (sm *SheetMapper) newSheetName(month time.Time) (string, bool) {
+	if m, ok := sm.mappingFor(month); ok {
+		return m.SheetName, true
+	}
+	if sm.template != nil {
+		return sm.template.Format(month), true
+	}
+	return "", false
+}
+
+// addMapping は月とシート名の対応を記録します（CSVに記載済みの月は変更しません）
+func (sm *SheetMapper) addMapping(month time.Time, sheetName string) {
+	if _, ok := sm.mappingFor(month); ok {
+		return
+	}
+	sm.mappings = append(sm.mappings, SheetMapping{Month: month, SheetName: sheetName})
+}
+
+// CreationEnabled はテンプレートのシートからの月のシートの作成が設定されているかどうかを返します
+func (p *SheetPlanner) CreationEnabled() bool {
+	return p.creation != nil
+}
+
+// CreateSheets は months のうちシートが存在しない月のシートを、テンプレートのシートを複製して作成します
+// 作成したシートにはその月のDATE列と曜日の列、ヘッダー行にない names を書き込み、月とシート名の対応を記録します
+// ドライランの場合は作成せず、作成するシートをログに出力します
+func (p *SheetPlanner) CreateSheets(ctx context.Context, months []time.Time, names []string) ([]SheetMapping, error) {
+	if p.creation == nil {
+		return nil, fmt.Errorf("sheet creation is not configured")
+	}
+
+	infos, err := p.api.ListSheets(ctx, p.spreadsheetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sheets: %v", err)
+	}
+
+	var template *SheetInfo
+	titles := make(map[string]bool, len(infos))
+	for i, info := range infos {
+		titles[info.Title] = true
+		if info.Title == p.creation.templateTab {
+			template = &infos[i]
+		}
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template sheet %q not found", p.creation.templateTab)
+	}
+
+	// テンプレートのヘッダー行にない名前を、ヘッダー行の最後の値の次の列から追加する
+	header, err := p.api.GetValues(ctx, p.spreadsheetID, sheetRange(template.Title, fmt.Sprintf("%d:%d", p.writer.headerRow, p.writer.headerRow)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row of template sheet: %v", err)
+	}
+	var headerValues []interface{}
+	if len(header) > 0 {
+		headerValues = header[0]
+	}
+	newNames, nameCol := p.namesToAdd(headerValues, names)
+
+	var created []SheetMapping
+	for _, month := range months {
+		sheetName, ok := p.writer.sheetMapper.newSheetName(month)
+		if !ok {
+			return created, fmt.Errorf("no sheet name for %s (SHEET_NAME_TEMPLATE is not set)", month.Format("2006-01"))
+		}
+
+		// 同じ名前のシートが既にある場合はそのシートを使用する
+		if titles[sheetName] {
+			p.writer.sheetMapper.addMapping(month, sheetName)
+			continue
+		}
+
+		if p.writer.options.DryRun {
+			log.Printf("ドライランのため、%s のシート %s は作成しません", month.Format("2006-01"), sheetName)
+			continue
+		}
+
+		info, err := p.api.DuplicateSheet(ctx, p.spreadsheetID, template.ID, sheetName, int64(len(titles)))
+		if err != nil {
+			return created, fmt.Errorf("failed to duplicate template sheet as %s: %v", sheetName, err)
+		}
+
+		// 日付を書き込めなかったシートを残すと、次回の実行で既存のシートとして扱われ日付が空のままになるため削除する
+		if err := p.api.BatchUpdateValues(ctx, p.spreadsheetID, p.newSheetValues(sheetName, month, newNames, nameCol)); err != nil {
+			if delErr := p.api.DeleteSheet(context.WithoutCancel(ctx), p.spreadsheetID, info.ID); delErr != nil {
+				return created, fmt.Errorf("failed to fill sheet %s: %v (and failed to delete it: %v)", sheetName, err, delErr)
+			}
+			return created, fmt.Errorf("failed to fill sheet %s (the sheet was deleted): %v", sheetName, err)
+		}
+		titles[sheetName] = true
+
+		p.writer.sheetMapper.addMapping(month, sheetName)
+		created = append(created, SheetMapping{Month: month, SheetName: sheetName})
+		log.Printf("%s のシート %s をテンプレート %s から作成しました", month.Format("2006-01"), sheetName, template.Title)
+	}
+	return created, nil
+}
+
+// namesToAdd はヘッダー行にない名前と、それらを書き込む最初の列（0始まり）を返します
+func (p *SheetPlanner) namesToAdd(header []interface{}, names []string) ([]interface{}, int) {
+	existing := make(map[string]bool, len(header))
+	for _, v := range header {
+		if s, ok := v.(string); ok {
+			existing[s] = true
+		}
+	}
+
+	var newNames []interface{}
+	for _, name := range names {
+		if name == "" || existing[name] {
+			continue
+		}
+		existing[name] = true
+		newNames = append(newNames, name)
+	}
+
+	// DATE列と曜日の列より右に追加する
+	col := max(len(header), columnNameToIndex(p.writer.dateCol)+1)
+	if p.creation != nil {
+		col = max(col, columnNameToIndex(p.creation.weekdayCol)+1)
+	}
+	return newNames, col
+}
+
+// headerNamesRange はヘッダー行の nameCol 列（0始まり）から newNames を書き込む範囲と値を返します
+func (p *SheetPlanner) headerNamesRange(sheetName string, newNames []interface{}, nameCol int) *sheets.ValueRange {
+	return &sheets.ValueRange{
+		Range: sheetRange(sheetName, fmt.Sprintf("%s%d:%s%d", columnIndexToName(nameCol), p.writer.headerRow,
+			columnIndexToName(nameCol+len(newNames)-1), p.writer.headerRow)),
+		Values: [][]interface{}{newNames},
+	}
+}
+
+// AddMissingNames は months の既存の月のシートのヘッダー行に、names のうちない名前を追加します
+// 列がないユーザーはその月のシートに書き込めず、すべての月の書き込みが失敗するためです
+// シートの作成が設定されていない場合とドライランの場合は追加せず、ない名前をログに出力します
+func (p *SheetPlanner) AddMissingNames(ctx context.Context, months []time.Time, names []string) error {
+	infos, err := p.api.ListSheets(ctx, p.spreadsheetID)
+	if err != nil {
+		return fmt.Errorf("failed to list sheets: %v", err)
+	}
+	titles := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		titles[info.Title] = true
+	}
+
+	checked := make(map[string]bool)
+	for _, month := range months {
+		sheetName := p.writer.sheetMapper.GetSheetName(month)
+		if sheetName == nil || !titles[*sheetName] || checked[*sheetName] {
+			continue
+		}
+		checked[*sheetName] = true
+
+		header, err := p.api.GetValues(ctx, p.spreadsheetID, sheetRange(*sheetName, fmt.Sprintf("%d:%d", p.writer.headerRow, p.writer.headerRow)))
+		if err != nil {
+			return fmt.Errorf("failed to read header row of sheet %s: %v", *sheetName, err)
+		}
+		var headerValues []interface{}
+		if len(header) > 0 {
+			headerValues = header[0]
+		}
+		newNames, nameCol := p.namesToAdd(headerValues, names)
+		if len(newNames) == 0 {
+			continue
+		}
+
+		if p.creation == nil {
+			log.Printf("警告: シート %s のヘッダー行に %v の列がありません。これらのユーザーの予定は書き込めません（SHEET_TEMPLATE_TAB を設定すると自動で追加します）", *sheetName, newNames)
+			continue
+		}
+		if p.writer.options.DryRun {
+			log.Printf("ドライランのため、シート %s のヘッダー行に %v を追加しません", *sheetName, newNames)
+			continue
+		}
+
+		if err := p.api.BatchUpdateValues(ctx, p.spreadsheetID, []*sheets.ValueRange{p.headerNamesRange(*sheetName, newNames, nameCol)}); err != nil {
+			return fmt.Errorf("failed to add names to header row of sheet %s: %v", *sheetName, err)
+		}
+		// 読み込み済みのヘッダー行は古いため読み直す
+		delete(p.layouts, *sheetName)
+		delete(p.layoutErrs, *sheetName)
+		log.Printf("シート %s のヘッダー行に %v を追加しました", *sheetName, newNames)
+	}
+	return nil
+}
+
+// newSheetValues は作成したシートに書き込むヘッダー行の名前、DATE列と曜日の列の値を返します
+// DATE列には日付のシリアル値を書き込みます（表示形式はテンプレートのシートの書式に従います）
+func (p *SheetPlanner) newSheetValues(sheetName string, month time.Time, newNames []interface{}, nameCol int) []*sheets.ValueRange {
+	firstRow := p.writer.headerRow + 1
+	lastRow := p.writer.headerRow + maxDaysInMonth
+
+	dates := make([][]interface{}, maxDaysInMonth)
+	weekdays := make([][]interface{}, maxDaysInMonth)
+	days := daysIn(month.Year(), month.Month())
+	for i := range dates {
+		if i >= days {
+			dates[i] = []interface{}{""}
+			weekdays[i] = []interface{}{""}
+			continue
+		}
+		date := time.Date(month.Year(), month.Month(), i+1, 0, 0, 0, 0, time.UTC)
+		dates[i] = []interface{}{float64(date.Sub(sheetsEpoch) / (24 * time.Hour))}
+		weekdays[i] = []interface{}{weekdayNames[date.Weekday()]}
+	}
+
+	data := []*sheets.ValueRange{
+		{
+			Range:  sheetRange(sheetName, fmt.Sprintf("%s%d:%s%d", p.writer.dateCol, firstRow, p.writer.dateCol, lastRow)),
+			Values: dates,
+		},
+		{
+			Range:  sheetRange(sheetName, fmt.Sprintf("%s%d:%s%d", p.creation.weekdayCol, firstRow, p.creation.weekdayCol, lastRow)),
+			Values: weekdays,
+		},
+	}
+	if len(newNames) > 0 {
+		data = append(data, p.headerNamesRange(sheetName, newNames, nameCol))
+	}
+	return data
+}
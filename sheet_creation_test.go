@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/eotel/garoon2gs/internal/client"
+	"google.golang.org/api/sheets/v4"
+	"testing"
+	"time"
+)
+
+// newCreationPlanner は2025年2月のシートと、テンプレートのシート「テンプレート」を持つ SheetPlanner を作成します
+func newCreationPlanner(t *testing.T, opts WriteOptions) (*SheetPlanner, *fakeSheetsAPI) {
+	writer, fake := newFebruaryWriter(t, opts)
+	writer.sheetMapper.template = mustTemplate(t, "R{reiwa_fy}年度_{month}月")
+
+	fake.addSheet("book", "テンプレート")
+	fake.set("book", "テンプレート!A7", "DATE")
+	fake.set("book", "テンプレート!B7", "DoW")
+	fake.set("book", "テンプレート!C7", "三浦")
+
+	planner := newSheetPlanner(fake, "book", writer)
+	planner.creation = &sheetCreation{templateTab: "テンプレート", weekdayCol: "B"}
+	return planner, fake
+}
+
+func TestSheetPlannerCreateSheets(t *testing.T) {
+	planner, fake := newCreationPlanner(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	ctx := context.Background()
+
+	months := monthsBetween(time.Date(2025, 2, 15, 0, 0, 0, 0, time.Local), time.Date(2025, 4, 30, 23, 59, 59, 0, time.Local))
+	created, err := planner.CreateSheets(ctx, months, []string{"三浦", "伊藤"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 既にある2月のシートは作成しない
+	if len(created) != 2 || created[0].SheetName != "R6年度_3月" || created[1].SheetName != "R7年度_4月" {
+		t.Fatalf("unexpected created sheets: %+v", created)
+	}
+	if fake.duplicates != 2 {
+		t.Errorf("expected 2 duplicated sheets but got %d", fake.duplicates)
+	}
+
+	expected := map[string]string{
+		"R6年度_3月!A7":  "DATE",
+		"R6年度_3月!C7":  "三浦",
+		"R6年度_3月!D7":  "伊藤",
+		"R6年度_3月!A8":  "45717", // 2025-03-01
+		"R6年度_3月!B8":  "土",
+		"R6年度_3月!A38": "45747", // 2025-03-31
+		"R6年度_3月!B38": "月",
+		"R7年度_4月!A37": "45777", // 2025-04-30
+		"R7年度_4月!B37": "水",
+		"R7年度_4月!A38": "",
+		"R7年度_4月!E7":  "",
+		"テンプレート!D7":   "",
+	}
+	for cell, value := range expected {
+		if got := fake.get("book", cell); got != value {
+			t.Errorf("%s: expected %q but got %q", cell, value, got)
+		}
+	}
+
+	// 作成したシートに予定を書き込める
+	if sheet := planner.writer.sheetMapper.GetSheetName(time.Date(2025, 3, 3, 0, 0, 0, 0, time.Local)); sheet == nil || *sheet != "R6年度_3月" {
+		t.Errorf("expected R6年度_3月 but got %v", sheet)
+	}
+	if err := planner.Add(ctx, "1", "伊藤", []client.Event{allDayEvent("有休", 2025, 3, 3)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failures := planner.Flush(ctx); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if got := fake.get("book", "R6年度_3月!D10"); got != "週休" {
+		t.Errorf("expected 週休 in R6年度_3月!D10 but got %q", got)
+	}
+
+	// 2回目は作成しない
+	created, err = planner.CreateSheets(ctx, months, []string{"三浦", "伊藤"})
+	if err != nil || len(created) != 0 {
+		t.Errorf("expected no sheets to be created but got %+v (err: %v)", created, err)
+	}
+}
+
+func TestSheetPlannerCreateSheetsDryRun(t *testing.T) {
+	planner, fake := newCreationPlanner(t, WriteOptions{DryRun: true, Report: &ChangeReport{}})
+
+	created, err := planner.CreateSheets(context.Background(), []time.Time{time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}, []string{"伊藤"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(created) != 0 || fake.duplicates != 0 || fake.batchUpdates != 0 {
+		t.Errorf("expected no changes in dry run but got %+v (%d duplicates, %d batch updates)", created, fake.duplicates, fake.batchUpdates)
+	}
+}
+
+// failingUpdateSheetsAPI は BatchUpdateValues が常に失敗するテスト用の SheetsAPI です
+type failingUpdateSheetsAPI struct {
+	*fakeSheetsAPI
+}
+
+func (f *failingUpdateSheetsAPI) BatchUpdateValues(ctx context.Context, spreadsheetID string, data []*sheets.ValueRange) error {
+	return errors.New("backend error")
+}
+
+func TestSheetPlannerCreateSheetsFillFailure(t *testing.T) {
+	planner, fake := newCreationPlanner(t, WriteOptions{})
+	planner.api = &failingUpdateSheetsAPI{fakeSheetsAPI: fake}
+	ctx := context.Background()
+	months := []time.Time{time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}
+
+	created, err := planner.CreateSheets(ctx, months, []string{"伊藤"})
+	if err == nil || len(created) != 0 {
+		t.Fatalf("expected fill failure but got %+v (err: %v)", created, err)
+	}
+
+	// 日付を書き込めなかったシートは削除する
+	if fake.duplicates != 1 || fake.deletes != 1 {
+		t.Errorf("expected 1 duplicate and 1 delete but got %d and %d", fake.duplicates, fake.deletes)
+	}
+	infos, _ := fake.ListSheets(ctx, "book")
+	for _, info := range infos {
+		if info.Title == "R6年度_3月" {
+			t.Fatalf("expected R6年度_3月 to be deleted but it remains")
+		}
+	}
+
+	// 次回の実行では改めて作成して日付を書き込む
+	planner.api = fake
+	created, err = planner.CreateSheets(ctx, months, []string{"伊藤"})
+	if err != nil || len(created) != 1 {
+		t.Fatalf("expected R6年度_3月 to be created but got %+v (err: %v)", created, err)
+	}
+	if got := fake.get("book", "R6年度_3月!A8"); got != "45717" {
+		t.Errorf("expected 45717 in R6年度_3月!A8 but got %q", got)
+	}
+}
+
+func TestSheetPlannerAddMissingNames(t *testing.T) {
+	months := []time.Time{time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}
+	names := []string{"三浦", "伊藤", "佐藤"}
+
+	t.Run("append", func(t *testing.T) {
+		planner, fake := newCreationPlanner(t, WriteOptions{
+			PastDates: PastDateAll,
+			Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+		})
+		ctx := context.Background()
+
+		// 列がないユーザーのレイアウトを読み込み済みでも、追加後に読み直す
+		if err := planner.Add(ctx, "3", "佐藤", []client.Event{allDayEvent("有休", 2025, 2, 3)}); err == nil {
+			t.Fatal("expected error for missing name column but got none")
+		}
+
+		// シートがない3月は対象外
+		if err := planner.AddMissingNames(ctx, months, names); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := fake.get("book", "R6年度_2月!E7"); got != "佐藤" {
+			t.Errorf("expected 佐藤 in R6年度_2月!E7 but got %q", got)
+		}
+		if fake.batchUpdates != 1 {
+			t.Errorf("expected 1 batch update but got %d", fake.batchUpdates)
+		}
+
+		if err := planner.Add(ctx, "3", "佐藤", []client.Event{allDayEvent("有休", 2025, 2, 3)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if failures := planner.Flush(ctx); len(failures) != 0 {
+			t.Fatalf("unexpected failures: %v", failures)
+		}
+		if got := fake.get("book", "R6年度_2月!E10"); got != "週休" {
+			t.Errorf("expected 週休 in R6年度_2月!E10 but got %q", got)
+		}
+
+		// 2回目は追加しない
+		if err := planner.AddMissingNames(ctx, months, names); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := fake.get("book", "R6年度_2月!F7"); got != "" {
+			t.Errorf("expected R6年度_2月!F7 to stay empty but got %q", got)
+		}
+	})
+
+	tests := []struct {
+		name string
+		opts WriteOptions
+		// creation が false の場合はシートの作成を設定しない
+		creation bool
+	}{
+		{name: "dry run", opts: WriteOptions{DryRun: true, Report: &ChangeReport{}}, creation: true},
+		{name: "creation disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planner, fake := newCreationPlanner(t, tt.opts)
+			if !tt.creation {
+				planner.creation = nil
+			}
+
+			if err := planner.AddMissingNames(context.Background(), months, names); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fake.batchUpdates != 0 {
+				t.Errorf("expected no batch updates but got %d", fake.batchUpdates)
+			}
+		})
+	}
+}
+
+func TestSheetPlannerCreateSheetsTemplateNotFound(t *testing.T) {
+	planner, _ := newCreationPlanner(t, WriteOptions{})
+	planner.creation.templateTab = "雛形"
+
+	if _, err := planner.CreateSheets(context.Background(), []time.Time{time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)}, []string{"伊藤"}); err == nil {
+		t.Error("expected error for missing template sheet but got none")
+	}
+}
+
+func TestLoadSheetCreation(t *testing.T) {
+	tests := []struct {
+		name        string
+		templateTab string
+		weekdayCol  string
+		expected    *sheetCreation
+		expectError bool
+	}{
+		{name: "disabled"},
+		{name: "default weekday column", templateTab: "テンプレート", expected: &sheetCreation{templateTab: "テンプレート", weekdayCol: "B"}},
+		{name: "weekday column", templateTab: "テンプレート", weekdayCol: "C", expected: &sheetCreation{templateTab: "テンプレート", weekdayCol: "C"}},
+		{name: "invalid weekday column", templateTab: "テンプレート", weekdayCol: "C1", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHEET_TEMPLATE_TAB", tt.templateTab)
+			t.Setenv("WEEKDAY_COL", tt.weekdayCol)
+
+			got, err := loadSheetCreation("A")
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sheetDiscovery はスプレッドシートのシート（タブ）一覧から月のシートを探すための設定です
+type sheetDiscovery struct {
+	patterns  []*sheetNameTemplate // シート名のパターン（SHEET_DISCOVERY_PATTERNS）
+	monthCell string               // 年月が入力されたセル（SHEET_MONTH_CELL、例: "B2"）
+}
+
+// loadSheetDiscovery は環境変数からシートの探索の設定を読み込みます
+// SHEET_DISCOVERY_PATTERNS と SHEET_MONTH_CELL のどちらも未指定の場合はnilを返します
+func loadSheetDiscovery() (*sheetDiscovery, error) {
+	patternsStr := os.Getenv("SHEET_DISCOVERY_PATTERNS")
+	monthCell := strings.TrimSpace(os.Getenv("SHEET_MONTH_CELL"))
+	if patternsStr == "" && monthCell == "" {
+		return nil, nil
+	}
+
+	d := &sheetDiscovery{monthCell: monthCell}
+	if patternsStr != "" {
+		var texts []string
+		if err := json.Unmarshal([]byte(patternsStr), &texts); err != nil {
+			return nil, fmt.Errorf("failed to parse SHEET_DISCOVERY_PATTERNS: %v", err)
+		}
+		for _, text := range texts {
+			pattern, err := parseSheetNameTemplate(text)
+			if err != nil {
+				return nil, err
+			}
+			d.patterns = append(d.patterns, pattern)
+		}
+	}
+
+	if monthCell != "" && !cellRefPattern.MatchString(monthCell) {
+		return nil, fmt.Errorf("invalid SHEET_MONTH_CELL %q (expected a cell like B2)", monthCell)
+	}
+	return d, nil
+}
+
+// cellRefPattern はA1記法の単一のセル（"B2" など）です
+var cellRefPattern = regexp.MustCompile(`^[A-Z]+[1-9]\d*$`)
+
+// AmbiguousMonth は複数のシートが見つかった月を表す構造体です
+type AmbiguousMonth struct {
+	Month  time.Time
+	Sheets []string
+}
+
+// SheetDiscoveryResult はシートの探索結果を表す構造体です
+type SheetDiscoveryResult struct {
+	Found     []SheetMapping   // 見つかった月とシート
+	Ambiguous []AmbiguousMonth // 複数のシートが見つかったため書き込まない月
+	Missing   []time.Time      // 対象期間内でシートが見つからない月
+}
+
+// DiscoveryEnabled はシートの探索が設定されているかどうかを返します
+func (sm *SheetMapper) DiscoveryEnabled() bool {
+	return sm.discovery != nil
+}
+
+// Discover はスプレッドシートのシート一覧を取得し、パターンまたは年月のセルから各シートの月を求めます
+// CSVに記載された月はCSVのシート名を優先し、複数のシートが見つかった月は書き込みの対象から除きます
+// 探索後は、見つかったシートのみを書き込みの対象とします（テンプレートからシート名を作成しません）
+func (sm *SheetMapper) Discover(ctx context.Context, api SheetsAPI, spreadsheetID string, from, to time.Time) (*SheetDiscoveryResult, error) {
+	if sm.discovery == nil {
+		return nil, fmt.Errorf("sheet discovery is not configured")
+	}
+
+	infos, err := api.ListSheets(ctx, spreadsheetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sheets: %v", err)
+	}
+
+	months, err := sm.discovery.monthsOf(ctx, api, spreadsheetID, infos)
+	if err != nil {
+		return nil, err
+	}
+
+	// 月ごとにシートをまとめる（シートの並び順を維持）
+	byMonth := make(map[time.Time][]string)
+	var order []time.Time
+	for _, info := range infos {
+		month, ok := months[info.Title]
+		if !ok {
+			continue
+		}
+		if _, overridden := sm.mappingFor(month); overridden {
+			continue
+		}
+		if byMonth[month] == nil {
+			order = append(order, month)
+		}
+		byMonth[month] = append(byMonth[month], info.Title)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].Before(order[j]) })
+
+	result := &SheetDiscoveryResult{}
+	for _, month := range order {
+		sheets := byMonth[month]
+		if len(sheets) > 1 {
+			result.Ambiguous = append(result.Ambiguous, AmbiguousMonth{Month: month, Sheets: sheets})
+			continue
+		}
+		result.Found = append(result.Found, SheetMapping{Month: month, SheetName: sheets[0]})
+	}
+
+	sm.mappings = append(sm.mappings, result.Found...)
+	sm.discovered = true
+
+	// 対象期間内の月のうち、CSVにも探索結果にもない月を報告する
+	for _, month := range monthsBetween(from, to) {
+		if _, ok := sm.mappingFor(month); ok {
+			continue
+		}
+		if len(byMonth[month]) > 1 {
+			continue
+		}
+		result.Missing = append(result.Missing, month)
+	}
+
+	return result, nil
+}
+
+// monthsOf は各シートの月をパターンまたは年月のセルから求めます（シート名 → 月）
+func (d *sheetDiscovery) monthsOf(ctx context.Context, api SheetsAPI, spreadsheetID string, infos []SheetInfo) (map[string]time.Time, error) {
+	months := make(map[string]time.Time)
+
+	// パターンに一致しないシートは年月のセルから求める
+	var unmatched []string
+	for _, info := range infos {
+		if month, ok := d.matchPattern(info.Title); ok {
+			months[info.Title] = month
+			continue
+		}
+		unmatched = append(unmatched, info.Title)
+	}
+
+	if d.monthCell == "" || len(unmatched) == 0 {
+		return months, nil
+	}
+
+	ranges := make([]string, 0, len(unmatched))
+	for _, title := range unmatched {
+		ranges = append(ranges, sheetRange(title, d.monthCell))
+	}
+	values, err := api.BatchGetValues(ctx, spreadsheetID, ranges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read month cells: %v", err)
+	}
+
+	for i, rows := range values {
+		if i >= len(unmatched) || len(rows) == 0 || len(rows[0]) == 0 {
+			continue
+		}
+		if month, ok := parseMonthCell(rows[0][0]); ok {
+			months[unmatched[i]] = month
+		}
+	}
+	return months, nil
+}
+
+// matchPattern はシート名がいずれかのパターンに一致する場合にその月を返します
+func (d *sheetDiscovery) matchPattern(title string) (time.Time, bool) {
+	for _, pattern := range d.patterns {
+		if month, ok := pattern.Parse(title); ok {
+			return month, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ymPrefix は "2025/5", "2025-05-01", "2025年5月度" などの先頭の年月です
+var ymPrefix = regexp.MustCompile(`^(\d{4})\s*[/\-.年]\s*(\d{1,2})(?:\D|$)`)
+
+// parseMonthCell は年月のセルの値（日付のシリアル値、"2025/05"、"2025年5月" など）から月を求めます
+func parseMonthCell(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		return monthFromSerial(int(v))
+	case string:
+		s := strings.TrimSpace(v)
+		if m := ymPrefix.FindStringSubmatch(s); m != nil {
+			year, _ := strconv.Atoi(m[1])
+			month, _ := strconv.Atoi(m[2])
+			if month < 1 || month > 12 {
+				return time.Time{}, false
+			}
+			return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local), true
+		}
+		if n, err := strconv.Atoi(s); err == nil {
+			return monthFromSerial(n)
+		}
+	}
+	return time.Time{}, false
+}
+
+// monthFromSerial は日付のシリアル値からその月の初日を求めます
+func monthFromSerial(n int) (time.Time, bool) {
+	if n <= 31 {
+		return time.Time{}, false
+	}
+	d := sheetsEpoch.AddDate(0, 0, n)
+	return time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.Local), true
+}
+
+// logDiscovery はシートの探索結果をログに出力します
+func logDiscovery(result *SheetDiscoveryResult) {
+	for _, m := range result.Found {
+		log.Printf("%s のシート: %s", m.Month.Format("2006-01"), m.SheetName)
+	}
+	for _, a := range result.Ambiguous {
+		log.Printf("警告: %s に該当するシートが複数あるため書き込みません: %s", a.Month.Format("2006-01"), strings.Join(a.Sheets, ", "))
+	}
+	for _, month := range result.Missing {
+		log.Printf("警告: %s に該当するシートが見つかりません", month.Format("2006-01"))
+	}
+}
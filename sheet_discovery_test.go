@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// mustTemplate はテスト用にテンプレートを解析します
+func mustTemplate(t *testing.T, text string) *sheetNameTemplate {
+	t.Helper()
+	template, err := parseSheetNameTemplate(text)
+	if err != nil {
+		t.Fatalf("failed to parse template %q: %v", text, err)
+	}
+	return template
+}
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.Local)
+}
+
+func TestSheetMapperDiscoverByPattern(t *testing.T) {
+	fake := newFakeSheetsAPI()
+	for _, title := range []string{"集計", "R7年度_4月", "R7年度_5月", "R7年度_6月", "2025年6月", "R7年度_4月_old"} {
+		fake.addSheet("book", title)
+	}
+
+	mapper := &SheetMapper{
+		template: mustTemplate(t, "R{reiwa_fy}年度_{month}月"),
+		discovery: &sheetDiscovery{patterns: []*sheetNameTemplate{
+			mustTemplate(t, "R{reiwa_fy}年度_{month}月"),
+			mustTemplate(t, "{year}年{month}月"),
+		}},
+	}
+
+	result, err := mapper.Discover(context.Background(), fake, "book", month(2025, 4), time.Date(2025, 7, 31, 23, 59, 59, 0, time.Local))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Found) != 2 || result.Found[0].SheetName != "R7年度_4月" || result.Found[1].SheetName != "R7年度_5月" {
+		t.Errorf("unexpected found sheets: %+v", result.Found)
+	}
+	if len(result.Ambiguous) != 1 || !result.Ambiguous[0].Month.Equal(month(2025, 6)) || len(result.Ambiguous[0].Sheets) != 2 {
+		t.Errorf("unexpected ambiguous months: %+v", result.Ambiguous)
+	}
+	if len(result.Missing) != 1 || !result.Missing[0].Equal(month(2025, 7)) {
+		t.Errorf("unexpected missing months: %v", result.Missing)
+	}
+
+	// 探索後は見つかったシートのみが対象（テンプレートから存在しないシート名を作らない）
+	if sheet := mapper.GetSheetName(time.Date(2025, 5, 10, 0, 0, 0, 0, time.Local)); sheet == nil || *sheet != "R7年度_5月" {
+		t.Errorf("expected R7年度_5月 but got %v", sheet)
+	}
+	for _, m := range []time.Time{month(2025, 6), month(2025, 7)} {
+		if sheet := mapper.GetSheetName(m); sheet != nil {
+			t.Errorf("%s: expected no sheet but got %q", m.Format("2006-01"), *sheet)
+		}
+	}
+	if got := mapper.GetMonthFromSheetName("R7年度_4月"); got == nil || !got.Equal(month(2025, 4)) {
+		t.Errorf("expected 2025-04 for R7年度_4月 but got %v", got)
+	}
+}
+
+func TestSheetMapperDiscoverByMonthCell(t *testing.T) {
+	fake := newFakeSheetsAPI()
+	fake.addSheet("book", "4月")
+	fake.addSheet("book", "5月")
+	fake.addSheet("book", "集計")
+	fake.addSheet("book", "6月（特別）")
+	fake.set("book", "4月!B2", "2025/04")
+	fake.set("book", "5月!B2", float64(45778)) // 2025-05-01
+	fake.set("book", "集計!B2", "合計")
+	fake.set("book", "6月（特別）!B2", "2025年6月度")
+
+	mapper := &SheetMapper{
+		mappings: []SheetMapping{
+			// CSVに記載された月はCSVのシートを優先する
+			{Month: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), SheetName: "6月"},
+		},
+		discovery: &sheetDiscovery{monthCell: "B2"},
+	}
+
+	result, err := mapper.Discover(context.Background(), fake, "book", month(2025, 4), time.Date(2025, 6, 30, 23, 59, 59, 0, time.Local))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Found) != 2 || result.Found[0].SheetName != "4月" || result.Found[1].SheetName != "5月" {
+		t.Errorf("unexpected found sheets: %+v", result.Found)
+	}
+	if len(result.Ambiguous) != 0 || len(result.Missing) != 0 {
+		t.Errorf("expected no ambiguous or missing months but got %+v / %v", result.Ambiguous, result.Missing)
+	}
+	if sheet := mapper.GetSheetName(month(2025, 6)); sheet == nil || *sheet != "6月" {
+		t.Errorf("expected CSV sheet 6月 but got %v", sheet)
+	}
+}
+
+func TestSheetMapperDiscoverByMonthCellQuotedTitles(t *testing.T) {
+	fake := newFakeSheetsAPI()
+	fake.addSheet("book", "集計 (全体)")
+	fake.addSheet("book", "7月 予定")
+	fake.addSheet("book", "Ito's 8月")
+	fake.set("book", "集計 (全体)!B2", "合計")
+	fake.set("book", "7月 予定!B2", "2025/07")
+	fake.set("book", "Ito's 8月!B2", "2025/08")
+
+	mapper := &SheetMapper{discovery: &sheetDiscovery{monthCell: "B2"}}
+
+	// 空白や記号を含むシート名も ' で囲んで読み取るため、一括読み取りが失敗しない
+	result, err := mapper.Discover(context.Background(), fake, "book", month(2025, 7), time.Date(2025, 8, 31, 23, 59, 59, 0, time.Local))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Found) != 2 || result.Found[0].SheetName != "7月 予定" || result.Found[1].SheetName != "Ito's 8月" {
+		t.Errorf("unexpected found sheets: %+v", result.Found)
+	}
+	if len(result.Missing) != 0 {
+		t.Errorf("expected no missing months but got %v", result.Missing)
+	}
+}
+
+func TestParseMonthCell(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected time.Time // ゼロ値の場合は解釈できない
+	}{
+		{"2025/04", month(2025, 4)},
+		{"2025-05-01", month(2025, 5)},
+		{"2025年6月度", month(2025, 6)},
+		{float64(45809), month(2025, 6)},
+		{"45809", month(2025, 6)},
+		{"2025/13", time.Time{}},
+		{"4月", time.Time{}},
+		{"", time.Time{}},
+		{nil, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseMonthCell(tt.value)
+		if tt.expected.IsZero() {
+			if ok {
+				t.Errorf("%v: expected no month but got %s", tt.value, got.Format("2006-01"))
+			}
+			continue
+		}
+		if !ok || !got.Equal(tt.expected) {
+			t.Errorf("%v: expected %s but got %s (ok: %v)", tt.value, tt.expected.Format("2006-01"), got.Format("2006-01"), ok)
+		}
+	}
+}
+
+func TestLoadSheetDiscovery(t *testing.T) {
+	t.Setenv("SHEET_DISCOVERY_PATTERNS", "")
+	t.Setenv("SHEET_MONTH_CELL", "")
+	if d, err := loadSheetDiscovery(); err != nil || d != nil {
+		t.Errorf("expected no discovery but got %+v (err: %v)", d, err)
+	}
+
+	t.Setenv("SHEET_DISCOVERY_PATTERNS", `["R{reiwa_fy}年度_{month}月"]`)
+	t.Setenv("SHEET_MONTH_CELL", "B2")
+	d, err := loadSheetDiscovery()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.patterns) != 1 || d.monthCell != "B2" {
+		t.Errorf("unexpected discovery: %+v", d)
+	}
+
+	t.Setenv("SHEET_MONTH_CELL", "B")
+	if _, err := loadSheetDiscovery(); err == nil {
+		t.Error("expected error for invalid SHEET_MONTH_CELL but got none")
+	}
+}
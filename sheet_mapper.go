@@ -6,7 +6,6 @@ import (
 	"github.com/eotel/garoon2gs/internal/client"
 	"log"
 	"os"
-	"path/filepath"
 	"time"
 )
 
@@ -17,17 +16,43 @@ type SheetMapping struct {
 }
 
 // SheetMapper はイベント日付からシート名を解決するマッパーです
+// SHEET_NAME_TEMPLATE が指定されている場合はテンプレートからシート名を求め、
+// SHEET_MAPPING_PATH のCSVに記載された月はCSVのシート名を優先します
 type SheetMapper struct {
 	mappings []SheetMapping
+	template *sheetNameTemplate // シート名のテンプレート（未指定の場合はnil）
+
+	discovery  *sheetDiscovery // シート一覧からの月のシートの探索の設定（未指定の場合はnil）
+	discovered bool            // Discover で探索済みの場合はtrue（テンプレートを使用しない）
 }
 
 // NewSheetMapper は新しいSheetMapperインスタンスを作成します
 func NewSheetMapper() (*SheetMapper, error) {
+	mapper := &SheetMapper{}
 
-	// 環境変数からCSVファイルのパスを取得
+	// シート名のテンプレートを解析
+	if text := os.Getenv("SHEET_NAME_TEMPLATE"); text != "" {
+		template, err := parseSheetNameTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+		mapper.template = template
+	}
+
+	// シート一覧から月のシートを探索する設定を読み込み
+	discovery, err := loadSheetDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	mapper.discovery = discovery
+
+	// 環境変数からCSVファイルのパスを取得（テンプレートまたは探索を使用する場合は省略可能）
 	csvPathFromEnv := os.Getenv("SHEET_MAPPING_PATH")
 	if csvPathFromEnv == "" {
-		return nil, fmt.Errorf("SHEET_MAPPING_PATH environment variable is not set")
+		if mapper.template == nil && mapper.discovery == nil {
+			return nil, fmt.Errorf("SHEET_MAPPING_PATH, SHEET_NAME_TEMPLATE or SHEET_DISCOVERY_PATTERNS/SHEET_MONTH_CELL environment variable must be set")
+		}
+		return mapper, nil
 	}
 
 	// 実行ファイルのディレクトリを取得
@@ -37,8 +62,17 @@ func NewSheetMapper() (*SheetMapper, error) {
 	}
 
 	// 絶対パスを構築
-	csvPath := filepath.Join(configDir, csvPathFromEnv)
+	csvPath := resolveConfigPath(configDir, csvPathFromEnv)
+
+	mapper.mappings, err = loadSheetMappings(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	return mapper, nil
+}
 
+// loadSheetMappings はシートマッピングのCSVファイルを読み込みます
+func loadSheetMappings(csvPath string) ([]SheetMapping, error) {
 	file, err := os.Open(csvPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open CSV file %s: %v", csvPath, err)
@@ -78,9 +112,7 @@ func NewSheetMapper() (*SheetMapper, error) {
 	}
 
 	log.Printf("Loaded %d sheet mappings from %s", len(mappings), csvPath)
-	return &SheetMapper{
-		mappings: mappings,
-	}, nil
+	return mappings, nil
 }
 
 // GetSheetName は指定された日付に対応するシート名を返します
@@ -89,10 +121,14 @@ func (sm *SheetMapper) GetSheetName(date time.Time) *string {
 	// 年月のみを比較するため、日付部分を初期化
 	targetMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
 
-	for _, m := range sm.mappings {
-		if m.Month.Year() == targetMonth.Year() && m.Month.Month() == targetMonth.Month() {
-			return &m.SheetName
-		}
+	if m, ok := sm.mappingFor(targetMonth); ok {
+		return &m.SheetName
+	}
+
+	// CSVに記載がない月はテンプレートから求める（探索済みの場合は見つかったシートのみ）
+	if sm.template != nil && !sm.discovered {
+		name := sm.template.Format(targetMonth)
+		return &name
 	}
 
 	log.Printf("Skipping event for %s: no sheet mapping found", date.Format("2006-01"))
@@ -108,6 +144,25 @@ func (sm *SheetMapper) GetMonthFromSheetName(sheetName string) *time.Time {
 		}
 	}
 
+	// テンプレートから読み取った月がCSVで別のシートに割り当てられている場合は対応しない
+	if sm.template != nil && !sm.discovered {
+		if month, ok := sm.template.Parse(sheetName); ok {
+			if _, overridden := sm.mappingFor(month); !overridden {
+				return &month
+			}
+		}
+	}
+
 	log.Printf("No month mapping found for sheet: %s", sheetName)
 	return nil
 }
+
+// mappingFor はCSVに記載された指定の月のマッピングを返します
+func (sm *SheetMapper) mappingFor(month time.Time) (SheetMapping, bool) {
+	for _, m := range sm.mappings {
+		if m.Month.Year() == month.Year() && m.Month.Month() == month.Month() {
+			return m, true
+		}
+	}
+	return SheetMapping{}, false
+}
@@ -154,3 +154,66 @@ func TestSheetMapperWithoutEnvVar(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestSheetMapperWithTemplate(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "sheet_mapping.csv")
+	csvContent := "month,sheet_name\n2025-08,R7年度_8月（夏季）\n"
+	if err := os.WriteFile(csvPath, []byte(csvContent), 0644); err != nil {
+		t.Fatalf("Failed to create test CSV: %v", err)
+	}
+	t.Setenv("SHEET_MAPPING_PATH", csvPath)
+	t.Setenv("SHEET_NAME_TEMPLATE", "R{reiwa_fy}年度_{month}月")
+
+	mapper, err := NewSheetMapper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// CSVに記載がない月はテンプレートから、記載がある月はCSVから求める
+	names := map[time.Time]string{
+		time.Date(2025, 7, 31, 0, 0, 0, 0, time.Local): "R7年度_7月",
+		time.Date(2025, 8, 1, 0, 0, 0, 0, time.Local):  "R7年度_8月（夏季）",
+		time.Date(2026, 1, 15, 0, 0, 0, 0, time.Local): "R7年度_1月",
+	}
+	for date, expected := range names {
+		if sheet := mapper.GetSheetName(date); sheet == nil || *sheet != expected {
+			t.Errorf("%s: expected %q but got %v", date.Format("2006-01-02"), expected, sheet)
+		}
+	}
+
+	months := map[string]*time.Time{
+		"R7年度_7月":     timePtr(time.Date(2025, 7, 1, 0, 0, 0, 0, time.Local)),
+		"R7年度_8月（夏季）": timePtr(time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)),
+		"R7年度_8月":     nil, // CSVで別のシートに割り当てられている
+		"集計":          nil,
+	}
+	for sheetName, expected := range months {
+		month := mapper.GetMonthFromSheetName(sheetName)
+		if (month == nil) != (expected == nil) || (month != nil && !month.Equal(*expected)) {
+			t.Errorf("%s: expected %v but got %v", sheetName, expected, month)
+		}
+	}
+}
+
+func TestSheetMapperTemplateOnly(t *testing.T) {
+	t.Setenv("SHEET_MAPPING_PATH", "")
+	t.Setenv("SHEET_NAME_TEMPLATE", "{year}年{month}月")
+
+	mapper, err := NewSheetMapper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sheet := mapper.GetSheetName(time.Date(2030, 11, 3, 0, 0, 0, 0, time.Local)); sheet == nil || *sheet != "2030年11月" {
+		t.Errorf("expected 2030年11月 but got %v", sheet)
+	}
+
+	t.Setenv("SHEET_NAME_TEMPLATE", "{month}月")
+	if _, err := NewSheetMapper(); err == nil {
+		t.Error("expected error for template without year but got none")
+	}
+}
+
+// timePtr は時刻のポインタを返すヘルパー関数です
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// era は和暦の元号を表す構造体です
+type era struct {
+	name  string
+	start time.Time // 元号の初日
+}
+
+// eras は新しい順に並べた元号の一覧です
+var eras = []era{
+	{"令和", time.Date(2019, 5, 1, 0, 0, 0, 0, time.Local)},
+	{"平成", time.Date(1989, 1, 8, 0, 0, 0, 0, time.Local)},
+	{"昭和", time.Date(1926, 12, 25, 0, 0, 0, 0, time.Local)},
+}
+
+// reiwaOffset は西暦から令和の年を求めるための差です
+const reiwaOffset = 2018
+
+// eraOf は指定された月の初日の元号を返します
+func eraOf(month time.Time) era {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
+	for _, e := range eras {
+		if !first.Before(e.start) {
+			return e
+		}
+	}
+	return eras[len(eras)-1]
+}
+
+// fiscalYear は4月始まりの年度を返します
+func fiscalYear(month time.Time) int {
+	if month.Month() < time.April {
+		return month.Year() - 1
+	}
+	return month.Year()
+}
+
+// placeholderPattern はテンプレート内のプレースホルダー（{year} など）です
+var placeholderPattern = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+// placeholders はプレースホルダーごとの値の求め方と、シート名から読み取る際の正規表現です
+var placeholders = map[string]struct {
+	format  func(month time.Time) string
+	pattern string
+}{
+	"year":     {func(m time.Time) string { return strconv.Itoa(m.Year()) }, `\d{4}`},
+	"month":    {func(m time.Time) string { return strconv.Itoa(int(m.Month())) }, `\d{1,2}`},
+	"month2":   {func(m time.Time) string { return fmt.Sprintf("%02d", int(m.Month())) }, `\d{2}`},
+	"fy":       {func(m time.Time) string { return strconv.Itoa(fiscalYear(m)) }, `\d{4}`},
+	"reiwa":    {func(m time.Time) string { return strconv.Itoa(m.Year() - reiwaOffset) }, `\d+`},
+	"reiwa_fy": {func(m time.Time) string { return strconv.Itoa(fiscalYear(m) - reiwaOffset) }, `\d+`},
+	"era":      {func(m time.Time) string { return eraOf(m).name }, `令和|平成|昭和`},
+	"era_year": {func(m time.Time) string { return strconv.Itoa(m.Year() - eraOf(m).start.Year() + 1) }, `\d+`},
+	"era_fy":   {func(m time.Time) string { return strconv.Itoa(fiscalYear(m) - eraOf(m).start.Year() + 1) }, `\d+`},
+}
+
+// sheetNameTemplate は年月からシート名を求めるためのテンプレートです（例: "R{reiwa_fy}年度_{month}月"）
+type sheetNameTemplate struct {
+	text    string
+	names   []string       // テンプレートに含まれるプレースホルダー（出現順）
+	pattern *regexp.Regexp // シート名からプレースホルダーの値を読み取る正規表現
+}
+
+// parseSheetNameTemplate はテンプレートを解析します
+// 年月を特定できるよう、月（{month} または {month2}）と年を表すプレースホルダーが必要です
+func parseSheetNameTemplate(text string) (*sheetNameTemplate, error) {
+	t := &sheetNameTemplate{text: text}
+
+	var re strings.Builder
+	re.WriteString("^")
+	last := 0
+	hasMonth, hasYear := false, false
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := text[loc[2]:loc[3]]
+		p, ok := placeholders[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder {%s} in sheet name template %q", name, text)
+		}
+
+		switch name {
+		case "month", "month2":
+			hasMonth = true
+		case "era":
+		default:
+			hasYear = true
+		}
+
+		re.WriteString(regexp.QuoteMeta(text[last:loc[0]]))
+		re.WriteString("(" + p.pattern + ")")
+		t.names = append(t.names, name)
+		last = loc[1]
+	}
+	re.WriteString(regexp.QuoteMeta(text[last:]))
+	re.WriteString("$")
+
+	if !hasMonth || !hasYear {
+		return nil, fmt.Errorf("sheet name template %q must contain {month} or {month2} and a year placeholder", text)
+	}
+
+	var err error
+	if t.pattern, err = regexp.Compile(re.String()); err != nil {
+		return nil, fmt.Errorf("invalid sheet name template %q: %v", text, err)
+	}
+	return t, nil
+}
+
+// Format は指定された月のシート名を返します
+func (t *sheetNameTemplate) Format(month time.Time) string {
+	return placeholderPattern.ReplaceAllStringFunc(t.text, func(s string) string {
+		return placeholders[s[1:len(s)-1]].format(month)
+	})
+}
+
+// Parse はシート名から年月を求めます
+// テンプレートに一致しないシート名や、値が矛盾するシート名（"R7年度_1月" の {month} と {year} が合わないなど）の場合は false を返します
+func (t *sheetNameTemplate) Parse(sheetName string) (time.Time, bool) {
+	m := t.pattern.FindStringSubmatch(sheetName)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	values := make(map[string]string, len(t.names))
+	for i, name := range t.names {
+		values[name] = m[i+1]
+	}
+
+	month, err := strconv.Atoi(values["month"])
+	if values["month"] == "" {
+		month, err = strconv.Atoi(values["month2"])
+	}
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+
+	// 各プレースホルダーから年の候補を求め、テンプレートで再びシート名にして一致するものを採用する
+	for _, year := range t.candidateYears(values, time.Month(month)) {
+		candidate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		if t.Format(candidate) == sheetName {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// candidateYears はプレースホルダーの値から西暦の年の候補を求めます
+func (t *sheetNameTemplate) candidateYears(values map[string]string, month time.Month) []int {
+	// 年度から年を求める（1〜3月は年度の翌年）
+	fromFiscal := func(fy int) int {
+		if month < time.April {
+			return fy + 1
+		}
+		return fy
+	}
+
+	// 元号の名前が含まれない場合はすべての元号を候補とする
+	candidateEras := eras
+	if name, ok := values["era"]; ok {
+		candidateEras = nil
+		for _, e := range eras {
+			if e.name == name {
+				candidateEras = append(candidateEras, e)
+			}
+		}
+	}
+
+	var years []int
+	for name, v := range values {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "year":
+			years = append(years, n)
+		case "fy":
+			years = append(years, fromFiscal(n))
+		case "reiwa":
+			years = append(years, n+reiwaOffset)
+		case "reiwa_fy":
+			years = append(years, fromFiscal(n+reiwaOffset))
+		case "era_year":
+			for _, e := range candidateEras {
+				years = append(years, e.start.Year()+n-1)
+			}
+		case "era_fy":
+			for _, e := range candidateEras {
+				years = append(years, fromFiscal(e.start.Year()+n-1))
+			}
+		}
+	}
+	return years
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSheetNameTemplateFormat(t *testing.T) {
+	tests := []struct {
+		template string
+		month    time.Time
+		expected string
+	}{
+		{"R{reiwa_fy}年度_{month}月", time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local), "R7年度_4月"},
+		{"R{reiwa_fy}年度_{month}月", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), "R7年度_3月"},
+		{"{year}-{month2}", time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local), "2025-05"},
+		{"FY{fy}_{month}月", time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), "FY2024_1月"},
+		{"令和{reiwa}年{month}月", time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local), "令和7年12月"},
+		{"{era}{era_year}年{month}月", time.Date(2019, 4, 1, 0, 0, 0, 0, time.Local), "平成31年4月"},
+		{"{era}{era_year}年{month}月", time.Date(2019, 5, 1, 0, 0, 0, 0, time.Local), "令和1年5月"},
+		{"{era}{era_fy}年度_{month}月", time.Date(2020, 2, 1, 0, 0, 0, 0, time.Local), "令和1年度_2月"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			template, err := parseSheetNameTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := template.Format(tt.month); got != tt.expected {
+				t.Errorf("expected %q but got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSheetNameTemplateParse(t *testing.T) {
+	tests := []struct {
+		template  string
+		sheetName string
+		expected  time.Time // ゼロ値の場合は一致しない
+	}{
+		{"R{reiwa_fy}年度_{month}月", "R7年度_4月", time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local)},
+		{"R{reiwa_fy}年度_{month}月", "R7年度_3月", time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"R{reiwa_fy}年度_{month}月", "R7年度_13月", time.Time{}},
+		{"R{reiwa_fy}年度_{month}月", "R7年度_4月_test", time.Time{}},
+		{"R{reiwa_fy}年度_{month}月", "集計", time.Time{}},
+		{"{year}-{month2}", "2025-05", time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)},
+		{"{year}-{month2}", "2025-5", time.Time{}},
+		{"{era}{era_year}年{month}月", "平成31年4月", time.Date(2019, 4, 1, 0, 0, 0, 0, time.Local)},
+		{"{era}{era_year}年{month}月", "令和1年5月", time.Date(2019, 5, 1, 0, 0, 0, 0, time.Local)},
+		{"{era}{era_year}年{month}月", "令和1年4月", time.Time{}}, // 令和は5月から
+		{"{year}年{month}月（R{reiwa}）", "2025年6月（R7）", time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)},
+		{"{year}年{month}月（R{reiwa}）", "2025年6月（R8）", time.Time{}}, // 年が矛盾する
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template+"/"+tt.sheetName, func(t *testing.T) {
+			template, err := parseSheetNameTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			month, ok := template.Parse(tt.sheetName)
+			if tt.expected.IsZero() {
+				if ok {
+					t.Errorf("expected no match but got %s", month.Format("2006-01"))
+				}
+				return
+			}
+			if !ok || !month.Equal(tt.expected) {
+				t.Errorf("expected %s but got %s (ok: %v)", tt.expected.Format("2006-01"), month.Format("2006-01"), ok)
+			}
+		})
+	}
+}
+
+func TestParseSheetNameTemplateErrors(t *testing.T) {
+	for _, text := range []string{
+		"R{reiwa_fy}年度",        // 月がない
+		"{month}月",             // 年がない
+		"{era}{month}月",        // 元号だけでは年が決まらない
+		"{year}_{month}_{day}", // 未知のプレースホルダー
+	} {
+		if _, err := parseSheetNameTemplate(text); err == nil {
+			t.Errorf("%q: expected error but got none", text)
+		}
+	}
+}
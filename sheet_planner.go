@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"log"
+	"sort"
+	"time"
+)
+
+// SheetPlanner は複数ユーザーの予定をシートごとにまとめて書き込むための構造体です
+// シートのヘッダー行とDATE列は最初に必要になった時に1回だけ読み取り、
+// すべてのユーザーのセルを Flush でシートごとに1回のバッチ更新で書き込みます
+// 複数のgoroutineから同時に使用することはできません
+type SheetPlanner struct {
+	api           SheetsAPI
+	spreadsheetID string
+	writer        *ScheduleWriter
+	creation      *sheetCreation // 見つからない月のシートの作成の設定（未指定の場合はnil）
+
+	layouts    map[string]*sheetLayout // シート名 → 読み取り済みのレイアウト
+	layoutErrs map[string]error        // シート名 → レイアウトの読み取りエラー
+	pending    map[string][]cellUpdate // シート名 → 書き込む内容
+	sheetUsers map[string][]string     // シート名 → 書き込み対象のユーザーID
+}
+
+// NewSheetPlanner は新しい SheetPlanner インスタンスを作成します
+func NewSheetPlanner(api SheetsAPI, spreadsheetID string, holidayMenus []string, opts WriteOptions) (*SheetPlanner, error) {
+	writer, err := NewScheduleWriter()
+	if err != nil {
+		return nil, fmt.Errorf("schedule writerの作成に失敗しました: %v", err)
+	}
+	writer.holidayMenus = holidayMenus
+	writer.options = opts
+
+	// シート名を取得するためのマッパーを作成
+	sheetMapper, err := NewSheetMapper()
+	if err != nil {
+		return nil, fmt.Errorf("sheet mapperの作成に失敗しました: %v", err)
+	}
+	writer.sheetMapper = sheetMapper
+
+	planner := newSheetPlanner(api, spreadsheetID, writer)
+
+	// テンプレートのシートから月のシートを作成する設定を読み込み
+	if planner.creation, err = loadSheetCreation(writer.dateCol); err != nil {
+		return nil, err
+	}
+	if planner.creation != nil && sheetMapper.template == nil {
+		return nil, fmt.Errorf("SHEET_TEMPLATE_TAB requires SHEET_NAME_TEMPLATE to name new sheets")
+	}
+
+	return planner, nil
+}
+
+func newSheetPlanner(api SheetsAPI, spreadsheetID string, writer *ScheduleWriter) *SheetPlanner {
+	return &SheetPlanner{
+		api:           api,
+		spreadsheetID: spreadsheetID,
+		writer:        writer,
+		layouts:       make(map[string]*sheetLayout),
+		layoutErrs:    make(map[string]error),
+		pending:       make(map[string][]cellUpdate),
+		sheetUsers:    make(map[string][]string),
+	}
+}
+
+// DiscoveryEnabled はシート一覧からの月のシートの探索が設定されているかどうかを返します
+func (p *SheetPlanner) DiscoveryEnabled() bool {
+	return p.writer.sheetMapper.DiscoveryEnabled()
+}
+
+// DiscoverSheets はスプレッドシートのシート一覧から月のシートを探します
+// from から to までの月のうち、シートが見つからない月と複数見つかった月を結果に含めます
+func (p *SheetPlanner) DiscoverSheets(ctx context.Context, from, to time.Time) (*SheetDiscoveryResult, error) {
+	return p.writer.sheetMapper.Discover(ctx, p.api, p.spreadsheetID, from, to)
+}
+
+// Add はユーザーの予定から書き込む内容を求めます（書き込みは Flush で行います）
+// いずれかのシートで名前の列が見つからないなどのエラーがあった場合、そのユーザーのセルは書き込みません
+func (p *SheetPlanner) Add(ctx context.Context, userID, userName string, events []client.Event) error {
+	planned, err := p.plan(ctx, userID, userName, events, nil)
+	if err != nil {
+		return err
+	}
+	p.commit(userID, planned)
+	return nil
+}
+
+// plan はユーザーの書き込む内容をシートごとに求めます（シート名 → 書き込む内容）
+// 求めた内容は commit するまで Flush の対象になりません
+func (p *SheetPlanner) plan(ctx context.Context, userID, userName string, events []client.Event, within func(day time.Time) bool) (map[string][]cellUpdate, error) {
+	eventsBySheet := groupEventsBySheet(p.writer.sheetMapper, events, within)
+
+	sheetNames := make([]string, 0, len(eventsBySheet))
+	for sheetName := range eventsBySheet {
+		sheetNames = append(sheetNames, sheetName)
+	}
+	sort.Strings(sheetNames)
+
+	p.writer.name = userName
+	p.writer.profile = p.writer.profiles[userID]
+	planned := make(map[string][]cellUpdate, len(sheetNames))
+	for _, sheetName := range sheetNames {
+		layout, err := p.layout(ctx, sheetName)
+		if err != nil {
+			return nil, fmt.Errorf("シート %s の読み取りに失敗しました: %v", sheetName, err)
+		}
+
+		updates, err := p.writer.planCells(layout, eventsBySheet[sheetName])
+		if err != nil {
+			return nil, fmt.Errorf("シート %s の更新内容の作成に失敗しました: %v", sheetName, err)
+		}
+		planned[sheetName] = updates
+	}
+	return planned, nil
+}
+
+// commit は plan で求めたユーザーの書き込む内容を Flush の対象に加えます
+func (p *SheetPlanner) commit(userID string, planned map[string][]cellUpdate) {
+	for sheetName, updates := range planned {
+		p.pending[sheetName] = append(p.pending[sheetName], updates...)
+		p.sheetUsers[sheetName] = append(p.sheetUsers[sheetName], userID)
+	}
+}
+
+// layout はシートのレイアウトを返します（読み取りは各シートで1回のみ）
+func (p *SheetPlanner) layout(ctx context.Context, sheetName string) (*sheetLayout, error) {
+	if layout, ok := p.layouts[sheetName]; ok {
+		return layout, nil
+	}
+	if err, ok := p.layoutErrs[sheetName]; ok {
+		return nil, err
+	}
+
+	layout, err := p.writer.loadLayout(ctx, p.api, p.spreadsheetID, sheetName)
+	if err != nil {
+		// キャンセルによる失敗は次回に再度読み取れるよう記録しない
+		if ctx.Err() == nil {
+			p.layoutErrs[sheetName] = err
+		}
+		return nil, err
+	}
+	p.layouts[sheetName] = layout
+	return layout, nil
+}
+
+// Flush は Add で求めたすべてのセルをシートごとにまとめて書き込みます
+// 書き込みに失敗したシートに含まれるユーザーのIDとエラーを返します
+func (p *SheetPlanner) Flush(ctx context.Context) map[string]error {
+	sheetNames := make([]string, 0, len(p.pending))
+	for sheetName := range p.pending {
+		sheetNames = append(sheetNames, sheetName)
+	}
+	sort.Strings(sheetNames)
+
+	failures := make(map[string]error)
+	for _, sheetName := range sheetNames {
+		log.Printf("シート %s に%d人分のセルを書き込みます", sheetName, len(p.sheetUsers[sheetName]))
+
+		err := p.writer.applyUpdates(ctx, p.api, p.spreadsheetID, sheetName, p.pending[sheetName])
+		if err != nil {
+			for _, userID := range p.sheetUsers[sheetName] {
+				if _, failed := failures[userID]; !failed {
+					failures[userID] = fmt.Errorf("シート %s の更新に失敗しました: %v", sheetName, err)
+				}
+			}
+		}
+		delete(p.pending, sheetName)
+		delete(p.sheetUsers, sheetName)
+	}
+	return failures
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// allDayEvent は指定された日の終日の予定を作成します
+func allDayEvent(menu string, year int, month time.Month, day int) client.Event {
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	return client.Event{
+		EventMenu: menu,
+		Start:     client.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:       client.EventDateTime{DateTime: start.AddDate(0, 0, 1).Format(time.RFC3339)},
+		IsAllDay:  true,
+	}
+}
+
+func TestSheetPlanner(t *testing.T) {
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	planner := newSheetPlanner(fake, "book", writer)
+	ctx := context.Background()
+
+	if err := planner.Add(ctx, "1", "伊藤", []client.Event{allDayEvent("有休", 2025, 2, 10)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := planner.Add(ctx, "2", "三浦", []client.Event{allDayEvent("外出", 2025, 2, 3)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := planner.Add(ctx, "3", "佐藤", []client.Event{allDayEvent("外出", 2025, 2, 3)}); err == nil {
+		t.Error("expected error for user not in header row but got none")
+	}
+
+	// ヘッダー行とDATE列の読み取りは1回のみ
+	if fake.reads != 2 {
+		t.Errorf("expected 2 reads while planning but got %d", fake.reads)
+	}
+
+	if failures := planner.Flush(ctx); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+
+	// 現在の値の読み取りと書き込みはシートごとに1回のみ
+	if fake.reads != 3 {
+		t.Errorf("expected 3 reads in total but got %d", fake.reads)
+	}
+	if fake.batchUpdates != 1 {
+		t.Errorf("expected 1 batch update but got %d", fake.batchUpdates)
+	}
+
+	expected := map[string]string{
+		"R6年度_2月!C8":  "渋谷",
+		"R6年度_2月!C10": "外出",
+		"R6年度_2月!D10": "渋谷",
+		"R6年度_2月!D17": "週休",
+		"R6年度_2月!E8":  "",
+	}
+	for cell, want := range expected {
+		if got := fake.get("book", cell); got != want {
+			t.Errorf("%s: expected %q but got %q", cell, want, got)
+		}
+	}
+}
+
+func TestSheetPlannerChunksLargeBatches(t *testing.T) {
+	writer, _ := newFebruaryWriter(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+
+	// 40人 × 28日 = 1120セルは2回に分けて書き込む
+	var names []string
+	for i := 1; i <= 40; i++ {
+		names = append(names, fmt.Sprintf("ユーザー%d", i))
+	}
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book", "R6年度_2月", 7, time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), names...)
+
+	planner := newSheetPlanner(fake, "book", writer)
+	ctx := context.Background()
+	for i, name := range names {
+		if err := planner.Add(ctx, fmt.Sprint(i+1), name, []client.Event{allDayEvent("外出", 2025, 2, 14)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if failures := planner.Flush(ctx); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+	if fake.batchUpdates != 2 {
+		t.Errorf("expected 2 batch updates but got %d", fake.batchUpdates)
+	}
+	if got := fake.get("book", "R6年度_2月!AP35"); got != "渋谷" {
+		t.Errorf("expected last cell to be written but got %q", got)
+	}
+}
+
+func TestSheetPlannerFlushFailure(t *testing.T) {
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	writer.sheetMapper.mappings = append(writer.sheetMapper.mappings,
+		SheetMapping{Month: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), SheetName: "R6年度_3月"})
+	fake.addMonthSheet("book", "R6年度_3月", 7, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "三浦", "伊藤")
+
+	planner := newSheetPlanner(fake, "book", writer)
+	ctx := context.Background()
+	if err := planner.Add(ctx, "1", "伊藤", []client.Event{allDayEvent("有休", 2025, 2, 10)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := planner.Add(ctx, "2", "三浦", []client.Event{allDayEvent("有休", 2025, 3, 10)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 書き込み前に3月のシートの名前が変更された場合、3月に書き込むユーザーのみ失敗する
+	fake.books["book"].sheets[1].title = "削除済み"
+
+	failures := planner.Flush(ctx)
+	if len(failures) != 1 || failures["2"] == nil {
+		t.Errorf("expected only user 2 to fail but got %v", failures)
+	}
+	if got := fake.get("book", "R6年度_2月!D17"); got != "週休" {
+		t.Errorf("expected February sheet to be written but got %q", got)
+	}
+}
+
+func TestNewSheetPlannerAcrossMonths(t *testing.T) {
+	cleanup := setupWriterTest(t)
+	defer cleanup()
+
+	csvPath := filepath.Join(t.TempDir(), "sheet_mapping.csv")
+	if err := os.WriteFile(csvPath, []byte("month,sheet_name\n2025-02,R6年度_2月\n2025-03,R6年度_3月\n"), 0644); err != nil {
+		t.Fatalf("failed to write sheet mapping: %v", err)
+	}
+	t.Setenv("SHEET_MAPPING_PATH", csvPath)
+
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book", "R6年度_2月", 7, time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local), "伊藤")
+	fake.addMonthSheet("book", "R6年度_3月", 7, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "伊藤")
+
+	// 2月27日から3月3日までの休暇
+	events := []client.Event{{
+		EventMenu: "有休",
+		Start:     client.EventDateTime{DateTime: time.Date(2025, 2, 27, 0, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		End:       client.EventDateTime{DateTime: time.Date(2025, 3, 4, 0, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		IsAllDay:  true,
+	}}
+	opts := WriteOptions{
+		Today: time.Date(2025, 2, 25, 0, 0, 0, 0, time.Local),
+		From:  time.Date(2025, 2, 25, 0, 0, 0, 0, time.Local),
+		To:    time.Date(2025, 3, 5, 23, 59, 59, 0, time.Local),
+	}
+
+	ctx := context.Background()
+	planner, err := NewSheetPlanner(fake, "book", []string{"有休"}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := planner.Add(ctx, "1", "伊藤", events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failures := planner.Flush(ctx); len(failures) > 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+
+	expected := map[string]string{
+		"R6年度_2月!C31": "", // 期間外
+		"R6年度_2月!C32": "渋谷",
+		"R6年度_2月!C34": "週休",
+		"R6年度_2月!C35": "週休",
+		"R6年度_3月!C10": "週休",
+		"R6年度_3月!C11": "渋谷",
+		"R6年度_3月!C12": "渋谷",
+		"R6年度_3月!C13": "", // 期間外
+	}
+	for cell, want := range expected {
+		if got := fake.get("book", cell); got != want {
+			t.Errorf("%s: expected %q but got %q", cell, want, got)
+		}
+	}
+}
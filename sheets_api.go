@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/api/sheets/v4"
+	"strings"
+)
+
+// SheetsAPI はスケジュールの書き込みに使用するGoogle Sheets APIの操作を表すインターフェースです
+type SheetsAPI interface {
+	// GetValues は指定された範囲の値を読み取ります
+	GetValues(ctx context.Context, spreadsheetID, rng string) ([][]interface{}, error)
+	// BatchGetValues は複数の範囲の値を読み取ります（結果は ranges と同じ順序）
+	BatchGetValues(ctx context.Context, spreadsheetID string, ranges []string) ([][][]interface{}, error)
+	// BatchUpdateValues は複数の範囲に値を書き込みます（RAW）
+	BatchUpdateValues(ctx context.Context, spreadsheetID string, data []*sheets.ValueRange) error
+	// ListSheets はスプレッドシートのシート（タブ）一覧を返します
+	ListSheets(ctx context.Context, spreadsheetID string) ([]SheetInfo, error)
+	// DuplicateSheet は sourceSheetID のシートを複製し、title の名前で index の位置に追加します
+	DuplicateSheet(ctx context.Context, spreadsheetID string, sourceSheetID int64, title string, index int64) (SheetInfo, error)
+	// DeleteSheet は sheetID のシートを削除します
+	DeleteSheet(ctx context.Context, spreadsheetID string, sheetID int64) error
+}
+
+// SheetInfo はスプレッドシート内のシート（タブ）の情報を表す構造体です
+type SheetInfo struct {
+	ID    int64
+	Title string
+	Index int64
+}
+
+// sheetRange はシート名とセル範囲（"B2", "7:7" など）からA1記法の範囲を作成します
+// シート名に空白や記号が含まれていても解析できるよう、常に ' で囲みます（シート名中の ' は2つ重ねます）
+func sheetRange(title, cells string) string {
+	return "'" + strings.ReplaceAll(title, "'", "''") + "'!" + cells
+}
+
+// googleSheetsAPI は *sheets.Service を使用する SheetsAPI の実装です
+type googleSheetsAPI struct {
+	srv *sheets.Service
+}
+
+// NewGoogleSheetsAPI は Google Sheets API を使用する SheetsAPI を作成します
+func NewGoogleSheetsAPI(srv *sheets.Service) SheetsAPI {
+	return &googleSheetsAPI{srv: srv}
+}
+
+func (g *googleSheetsAPI) GetValues(ctx context.Context, spreadsheetID, rng string) ([][]interface{}, error) {
+	resp, err := g.srv.Spreadsheets.Values.Get(spreadsheetID, rng).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+func (g *googleSheetsAPI) BatchGetValues(ctx context.Context, spreadsheetID string, ranges []string) ([][][]interface{}, error) {
+	resp, err := g.srv.Spreadsheets.Values.BatchGet(spreadsheetID).Ranges(ranges...).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][][]interface{}, len(ranges))
+	for i, vr := range resp.ValueRanges {
+		if i < len(values) {
+			values[i] = vr.Values
+		}
+	}
+	return values, nil
+}
+
+func (g *googleSheetsAPI) BatchUpdateValues(ctx context.Context, spreadsheetID string, data []*sheets.ValueRange) error {
+	req := &sheets.BatchUpdateValuesRequest{
+		ValueInputOption: "RAW",
+		Data:             data,
+	}
+	_, err := g.srv.Spreadsheets.Values.BatchUpdate(spreadsheetID, req).Context(ctx).Do()
+	return err
+}
+
+func (g *googleSheetsAPI) ListSheets(ctx context.Context, spreadsheetID string) ([]SheetInfo, error) {
+	resp, err := g.srv.Spreadsheets.Get(spreadsheetID).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SheetInfo, 0, len(resp.Sheets))
+	for _, s := range resp.Sheets {
+		if s.Properties == nil {
+			continue
+		}
+		infos = append(infos, SheetInfo{
+			ID:    s.Properties.SheetId,
+			Title: s.Properties.Title,
+			Index: s.Properties.Index,
+		})
+	}
+	return infos, nil
+}
+
+func (g *googleSheetsAPI) DuplicateSheet(ctx context.Context, spreadsheetID string, sourceSheetID int64, title string, index int64) (SheetInfo, error) {
+	req := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			DuplicateSheet: &sheets.DuplicateSheetRequest{
+				SourceSheetId:    sourceSheetID,
+				NewSheetName:     title,
+				InsertSheetIndex: index,
+				ForceSendFields:  []string{"InsertSheetIndex"},
+			},
+		}},
+	}
+	resp, err := g.srv.Spreadsheets.BatchUpdate(spreadsheetID, req).Context(ctx).Do()
+	if err != nil {
+		return SheetInfo{}, err
+	}
+
+	if len(resp.Replies) == 0 || resp.Replies[0].DuplicateSheet == nil || resp.Replies[0].DuplicateSheet.Properties == nil {
+		return SheetInfo{}, fmt.Errorf("no properties returned for duplicated sheet %s", title)
+	}
+	props := resp.Replies[0].DuplicateSheet.Properties
+	return SheetInfo{ID: props.SheetId, Title: props.Title, Index: props.Index}, nil
+}
+
+func (g *googleSheetsAPI) DeleteSheet(ctx context.Context, spreadsheetID string, sheetID int64) error {
+	req := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			DeleteSheet: &sheets.DeleteSheetRequest{
+				SheetId:         sheetID,
+				ForceSendFields: []string{"SheetId"},
+			},
+		}},
+	}
+	_, err := g.srv.Spreadsheets.BatchUpdate(spreadsheetID, req).Context(ctx).Do()
+	return err
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpreadsheetRoute はチームと年度ごとの書き込み先のスプレッドシートを表す構造体です
+type SpreadsheetRoute struct {
+	Team          string // チーム（空の場合はすべてのチーム）
+	FiscalYear    int    // 4月始まりの年度（0の場合はすべての年度）
+	SpreadsheetID string
+}
+
+// SpreadsheetRouter はユーザーと月から書き込み先のスプレッドシートを求めます
+// ユーザーマッピングの spreadsheet_id、SPREADSHEET_MAPPING_PATH のCSV、SPREADSHEET_ID の順に優先します
+type SpreadsheetRouter struct {
+	routes    []SpreadsheetRoute
+	defaultID string // SPREADSHEET_ID（未指定の場合は空）
+}
+
+// NewSpreadsheetRouter は環境変数から SpreadsheetRouter を作成します
+func NewSpreadsheetRouter(configDir string) (*SpreadsheetRouter, error) {
+	router := &SpreadsheetRouter{defaultID: os.Getenv("SPREADSHEET_ID")}
+
+	csvPathFromEnv := os.Getenv("SPREADSHEET_MAPPING_PATH")
+	if csvPathFromEnv == "" {
+		if router.defaultID == "" {
+			return nil, fmt.Errorf("SPREADSHEET_ID or SPREADSHEET_MAPPING_PATH environment variable must be set")
+		}
+		return router, nil
+	}
+
+	var err error
+	router.routes, err = loadSpreadsheetRoutes(resolveConfigPath(configDir, csvPathFromEnv))
+	if err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// loadSpreadsheetRoutes は書き込み先のスプレッドシートのCSVファイル（team,fiscal_year,spreadsheet_id）を読み込みます
+func loadSpreadsheetRoutes(csvPath string) ([]SpreadsheetRoute, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file %s: %v", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	if len(header) != 3 || header[0] != "team" || header[1] != "fiscal_year" || header[2] != "spreadsheet_id" {
+		return nil, fmt.Errorf("invalid CSV header format: expected [team,fiscal_year,spreadsheet_id] but got %v", header)
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV records: %v", err)
+	}
+
+	var routes []SpreadsheetRoute
+	for _, record := range records {
+		route := SpreadsheetRoute{
+			Team:          strings.TrimSpace(record[0]),
+			SpreadsheetID: strings.TrimSpace(record[2]),
+		}
+		if fy := strings.TrimSpace(record[1]); fy != "" {
+			if route.FiscalYear, err = strconv.Atoi(fy); err != nil {
+				return nil, fmt.Errorf("failed to parse fiscal year %q: %v", fy, err)
+			}
+		}
+		if route.SpreadsheetID == "" {
+			return nil, fmt.Errorf("spreadsheet_id is empty for team %q, fiscal year %q", record[0], record[1])
+		}
+		routes = append(routes, route)
+	}
+
+	log.Printf("Loaded %d spreadsheet mappings from %s", len(routes), csvPath)
+	return routes, nil
+}
+
+// SpreadsheetFor はユーザーの指定の月の書き込み先のスプレッドシートIDを返します
+// CSVはチームと年度が一致する行、チームのみ一致する行（年度が空）、チームが空の行の順に優先します
+func (r *SpreadsheetRouter) SpreadsheetFor(user mapping.UserMapping, month time.Time) (string, error) {
+	if user.SpreadsheetID != "" {
+		return user.SpreadsheetID, nil
+	}
+
+	fy := fiscalYear(month)
+	best, bestScore := "", -1
+	for _, route := range r.routes {
+		if route.Team != "" && route.Team != user.Team {
+			continue
+		}
+		if route.FiscalYear != 0 && route.FiscalYear != fy {
+			continue
+		}
+
+		score := 0
+		if route.Team != "" {
+			score += 2
+		}
+		if route.FiscalYear != 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = route.SpreadsheetID, score
+		}
+	}
+	if best != "" {
+		return best, nil
+	}
+
+	if r.defaultID != "" {
+		return r.defaultID, nil
+	}
+	return "", fmt.Errorf("no spreadsheet for user %s (team %q, fiscal year %d)", user.UserID, user.Team, fy)
+}
+
+// spreadsheetDestinations は書き込み先のスプレッドシートごとの SheetPlanner をまとめて扱うための構造体です
+type spreadsheetDestinations struct {
+	router   *SpreadsheetRouter
+	order    []string                         // スプレッドシートID（最初に現れた順）
+	planners map[string]*SheetPlanner         // スプレッドシートID → SheetPlanner
+	months   map[string][]time.Time           // スプレッドシートID → 書き込み対象の月
+	users    map[string][]mapping.UserMapping // スプレッドシートID → 書き込み対象のユーザー
+}
+
+// newSpreadsheetDestinations は users の months の各月の書き込み先を求め、スプレッドシートごとに SheetPlanner を作成します
+func newSpreadsheetDestinations(router *SpreadsheetRouter, users []mapping.UserMapping, months []time.Time, newPlanner func(spreadsheetID string) (*SheetPlanner, error)) (*spreadsheetDestinations, error) {
+	d := &spreadsheetDestinations{
+		router:   router,
+		planners: make(map[string]*SheetPlanner),
+		months:   make(map[string][]time.Time),
+		users:    make(map[string][]mapping.UserMapping),
+	}
+
+	for _, user := range users {
+		added := make(map[string]bool)
+		for _, month := range months {
+			id, err := router.SpreadsheetFor(user, month)
+			if err != nil {
+				return nil, err
+			}
+
+			if d.planners[id] == nil {
+				planner, err := newPlanner(id)
+				if err != nil {
+					return nil, err
+				}
+				d.planners[id] = planner
+				d.order = append(d.order, id)
+			}
+			if !containsMonth(d.months[id], month) {
+				d.months[id] = append(d.months[id], month)
+			}
+			if !added[id] {
+				d.users[id] = append(d.users[id], user)
+				added[id] = true
+			}
+		}
+	}
+	return d, nil
+}
+
+// containsMonth は months に month が含まれるかどうかを返します
+func containsMonth(months []time.Time, month time.Time) bool {
+	for _, m := range months {
+		if m.Equal(month) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMonths は months のうち spreadsheetID に書き込む月のみを返します
+func (d *spreadsheetDestinations) filterMonths(spreadsheetID string, months []time.Time) []time.Time {
+	var filtered []time.Time
+	for _, month := range months {
+		if containsMonth(d.months[spreadsheetID], month) {
+			filtered = append(filtered, month)
+		}
+	}
+	return filtered
+}
+
+// headerNames は users のうち spreadsheetID に書き込む月があるユーザーのヘッダー行の名前を返します
+// --users で絞り込む前のユーザーを渡し、絞り込んで実行してもシートに全員の列を用意するために使用します
+func (d *spreadsheetDestinations) headerNames(spreadsheetID string, users []mapping.UserMapping) []string {
+	var names []string
+	for _, user := range users {
+		for _, month := range d.months[spreadsheetID] {
+			if id, err := d.router.SpreadsheetFor(user, month); err == nil && id == spreadsheetID {
+				names = append(names, user.HeaderName)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// Add はユーザーの予定を日ごとの書き込み先のスプレッドシートの SheetPlanner に追加します
+// いずれかのスプレッドシートで書き込む内容を求められなかった場合は、どのスプレッドシートにもそのユーザーのセルを追加しません
+func (d *spreadsheetDestinations) Add(ctx context.Context, user mapping.UserMapping, events []client.Event) error {
+	planned := make(map[string]map[string][]cellUpdate, len(d.order))
+	for _, id := range d.order {
+		if !containsUser(d.users[id], user.UserID) {
+			continue
+		}
+
+		within := func(day time.Time) bool {
+			dest, err := d.router.SpreadsheetFor(user, day)
+			return err == nil && dest == id
+		}
+		cells, err := d.planners[id].plan(ctx, user.UserID, user.HeaderName, events, within)
+		if err != nil {
+			if len(d.order) > 1 {
+				return fmt.Errorf("スプレッドシート %s: %v", id, err)
+			}
+			return err
+		}
+		planned[id] = cells
+	}
+
+	// すべてのスプレッドシートで求められた場合のみ書き込みの対象に加える
+	for _, id := range d.order {
+		if cells, ok := planned[id]; ok {
+			d.planners[id].commit(user.UserID, cells)
+		}
+	}
+	return nil
+}
+
+// containsUser は users に userID のユーザーが含まれるかどうかを返します
+func containsUser(users []mapping.UserMapping, userID string) bool {
+	for _, u := range users {
+		if u.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush はすべてのスプレッドシートに書き込み、書き込みに失敗したユーザーのIDとエラーを返します
+func (d *spreadsheetDestinations) Flush(ctx context.Context) map[string]error {
+	failures := make(map[string]error)
+	for _, id := range d.order {
+		if len(d.order) > 1 {
+			log.Printf("スプレッドシート %s に書き込みます", id)
+		}
+		for userID, err := range d.planners[id].Flush(ctx) {
+			if _, failed := failures[userID]; !failed {
+				failures[userID] = err
+			}
+		}
+	}
+	return failures
+}
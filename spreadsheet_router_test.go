@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"github.com/eotel/garoon2gs/internal/client"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpreadsheetRouter(t *testing.T) {
+	router := &SpreadsheetRouter{
+		routes: []SpreadsheetRoute{
+			{Team: "", FiscalYear: 2025, SpreadsheetID: "book-2025"},
+			{Team: "営業", FiscalYear: 0, SpreadsheetID: "book-sales"},
+			{Team: "営業", FiscalYear: 2025, SpreadsheetID: "book-sales-2025"},
+		},
+		defaultID: "book-default",
+	}
+
+	tests := []struct {
+		name     string
+		user     mapping.UserMapping
+		month    time.Time
+		expected string
+	}{
+		{"team and fiscal year", mapping.UserMapping{UserID: "1", Team: "営業"}, time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local), "book-sales-2025"},
+		{"team only", mapping.UserMapping{UserID: "1", Team: "営業"}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "book-sales"},
+		{"fiscal year only", mapping.UserMapping{UserID: "2", Team: "開発"}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local), "book-2025"},
+		{"default", mapping.UserMapping{UserID: "3"}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "book-default"},
+		{"user spreadsheet", mapping.UserMapping{UserID: "4", Team: "営業", SpreadsheetID: "book-user"}, time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local), "book-user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := router.SpreadsheetFor(tt.user, tt.month)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s but got %s", tt.expected, got)
+			}
+		})
+	}
+
+	router.defaultID = ""
+	if _, err := router.SpreadsheetFor(mapping.UserMapping{UserID: "3"}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)); err == nil {
+		t.Error("expected error for user without spreadsheet but got none")
+	}
+}
+
+func TestLoadSpreadsheetRoutes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	routes, err := loadSpreadsheetRoutes(write("valid.csv", "team,fiscal_year,spreadsheet_id\n営業,2025,book-a\n,,book-b\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 2 || routes[0] != (SpreadsheetRoute{Team: "営業", FiscalYear: 2025, SpreadsheetID: "book-a"}) ||
+		routes[1] != (SpreadsheetRoute{SpreadsheetID: "book-b"}) {
+		t.Errorf("unexpected routes: %+v", routes)
+	}
+
+	for name, content := range map[string]string{
+		"header.csv":      "team,spreadsheet_id\n営業,book-a\n",
+		"fiscal_year.csv": "team,fiscal_year,spreadsheet_id\n営業,R7,book-a\n",
+		"empty_id.csv":    "team,fiscal_year,spreadsheet_id\n営業,2025,\n",
+	} {
+		if _, err := loadSpreadsheetRoutes(write(name, content)); err == nil {
+			t.Errorf("%s: expected error but got none", name)
+		}
+	}
+}
+
+func TestSpreadsheetDestinations(t *testing.T) {
+	t.Setenv("HEADER_ROW", "7")
+	t.Setenv("DATE_COL", "A")
+	t.Setenv("OUTING_MENUS", `["外出"]`)
+	t.Setenv("SHEET_NAME_TEMPLATE", "R{reiwa_fy}年度_{month}月")
+	for _, key := range []string{"SHEET_MAPPING_PATH", "SHEET_DISCOVERY_PATTERNS", "SHEET_MONTH_CELL", "SHEET_TEMPLATE_TAB", "RULES_PATH"} {
+		t.Setenv(key, "")
+	}
+
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local)
+
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book-sales-2024", "R6年度_3月", 7, march, "伊藤")
+	fake.addMonthSheet("book-sales-2025", "R7年度_4月", 7, april, "伊藤")
+	fake.addMonthSheet("book-main", "R6年度_3月", 7, march, "三浦")
+	fake.addMonthSheet("book-main", "R7年度_4月", 7, april, "三浦")
+
+	router := &SpreadsheetRouter{
+		routes: []SpreadsheetRoute{
+			{Team: "営業", FiscalYear: 2024, SpreadsheetID: "book-sales-2024"},
+			{Team: "営業", FiscalYear: 2025, SpreadsheetID: "book-sales-2025"},
+		},
+		defaultID: "book-main",
+	}
+	users := []mapping.UserMapping{
+		{UserID: "1", HeaderName: "伊藤", Team: "営業"},
+		{UserID: "2", HeaderName: "三浦"},
+	}
+	opts := WriteOptions{PastDates: PastDateAll, Today: march}
+
+	d, err := newSpreadsheetDestinations(router, users, []time.Time{march, april}, func(spreadsheetID string) (*SheetPlanner, error) {
+		return NewSheetPlanner(fake, spreadsheetID, []string{"有休"}, opts)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedOrder := []string{"book-sales-2024", "book-sales-2025", "book-main"}
+	if len(d.order) != len(expectedOrder) {
+		t.Fatalf("expected spreadsheets %v but got %v", expectedOrder, d.order)
+	}
+	for i, id := range expectedOrder {
+		if d.order[i] != id {
+			t.Errorf("expected spreadsheets %v but got %v", expectedOrder, d.order)
+		}
+	}
+	if got := d.filterMonths("book-sales-2025", []time.Time{march, april}); len(got) != 1 || !got[0].Equal(april) {
+		t.Errorf("expected only April for book-sales-2025 but got %v", got)
+	}
+
+	// ヘッダー行の名前は、書き込み対象でないユーザーも含めて書き込み先のスプレッドシートごとに求める
+	all := append(users, mapping.UserMapping{UserID: "3", HeaderName: "佐藤", Team: "営業"})
+	if got := d.headerNames("book-sales-2025", all); len(got) != 2 || got[0] != "伊藤" || got[1] != "佐藤" {
+		t.Errorf("expected [伊藤 佐藤] for book-sales-2025 but got %v", got)
+	}
+	if got := d.headerNames("book-main", all); len(got) != 1 || got[0] != "三浦" {
+		t.Errorf("expected [三浦] for book-main but got %v", got)
+	}
+
+	// 年度をまたぐ予定は日ごとにそれぞれのスプレッドシートに書き込む
+	ctx := context.Background()
+	vacation := client.Event{
+		EventMenu: "有休",
+		Start:     client.EventDateTime{DateTime: time.Date(2025, 3, 31, 0, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		End:       client.EventDateTime{DateTime: time.Date(2025, 4, 2, 0, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		IsAllDay:  true,
+	}
+	if err := d.Add(ctx, users[0], []client.Event{vacation}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.Add(ctx, users[1], []client.Event{allDayEvent("外出", 2025, 3, 31), allDayEvent("外出", 2025, 4, 1)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failures := d.Flush(ctx); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+
+	expected := map[[2]string]string{
+		{"book-sales-2024", "R6年度_3月!C38"}: "週休",
+		{"book-sales-2025", "R7年度_4月!C8"}:  "週休",
+		{"book-main", "R6年度_3月!C38"}:       "外出",
+		{"book-main", "R7年度_4月!C8"}:        "外出",
+	}
+	for cell, value := range expected {
+		if got := fake.get(cell[0], cell[1]); got != value {
+			t.Errorf("%s %s: expected %q but got %q", cell[0], cell[1], value, got)
+		}
+	}
+}
+
+func TestSpreadsheetDestinationsAddIsAllOrNothing(t *testing.T) {
+	t.Setenv("HEADER_ROW", "7")
+	t.Setenv("DATE_COL", "A")
+	t.Setenv("OUTING_MENUS", `["外出"]`)
+	t.Setenv("SHEET_NAME_TEMPLATE", "R{reiwa_fy}年度_{month}月")
+	for _, key := range []string{"SHEET_MAPPING_PATH", "SHEET_DISCOVERY_PATTERNS", "SHEET_MONTH_CELL", "SHEET_TEMPLATE_TAB", "RULES_PATH"} {
+		t.Setenv(key, "")
+	}
+
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	april := time.Date(2025, 4, 1, 0, 0, 0, 0, time.Local)
+
+	// 2025年度のスプレッドシートには伊藤の列がない
+	fake := newFakeSheetsAPI()
+	fake.addMonthSheet("book-2024", "R6年度_3月", 7, march, "伊藤")
+	fake.addMonthSheet("book-2025", "R7年度_4月", 7, april, "三浦")
+
+	router := &SpreadsheetRouter{
+		routes: []SpreadsheetRoute{
+			{FiscalYear: 2024, SpreadsheetID: "book-2024"},
+			{FiscalYear: 2025, SpreadsheetID: "book-2025"},
+		},
+	}
+	user := mapping.UserMapping{UserID: "1", HeaderName: "伊藤"}
+	opts := WriteOptions{PastDates: PastDateAll, Today: march}
+
+	d, err := newSpreadsheetDestinations(router, []mapping.UserMapping{user}, []time.Time{march, april}, func(spreadsheetID string) (*SheetPlanner, error) {
+		return NewSheetPlanner(fake, spreadsheetID, nil, opts)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Add(ctx, user, []client.Event{allDayEvent("外出", 2025, 3, 31), allDayEvent("外出", 2025, 4, 1)}); err == nil {
+		t.Fatal("expected error for missing name column but got none")
+	}
+
+	// 失敗したユーザーのセルは、求められたスプレッドシートにも書き込まない
+	if failures := d.Flush(ctx); len(failures) != 0 {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+	if fake.batchUpdates != 0 {
+		t.Errorf("expected no batch updates but got %d", fake.batchUpdates)
+	}
+	if got := fake.get("book-2024", "R6年度_3月!C38"); got != "" {
+		t.Errorf("expected R6年度_3月!C38 to stay empty but got %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"github.com/eotel/garoon2gs/internal/rules"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultOffDayLabel は勤務しない曜日に書き込むラベルのデフォルト値です（OFF_DAY_LABEL で変更できます）
+const defaultOffDayLabel = "週休"
+
+// userProfile はユーザーマッピングの place, work_days, rules 列で指定されたユーザーごとの設定です
+// 指定のない項目は共通の設定（NORMAL_PLACE、RULES_PATH など）を使用します
+type userProfile struct {
+	place    string                // 通常の勤務地（空の場合は NORMAL_PLACE）
+	workDays map[time.Weekday]bool // 勤務する曜日（nilの場合はすべての曜日）
+	rules    *rules.RuleSet        // このユーザーのルール（nilの場合は共通のルール）
+}
+
+// worksOn はユーザーが指定の曜日に勤務するかどうかを返します
+func (p *userProfile) worksOn(day time.Weekday) bool {
+	return p == nil || p.workDays == nil || p.workDays[day]
+}
+
+// loadUserProfiles はユーザーマッピングからユーザーID → ユーザーごとの設定を作成します
+// 設定のないユーザーは含めません。ルールファイルは同じパスを1回だけ読み込みます
+func loadUserProfiles(configDir string, users []mapping.UserMapping) (map[string]*userProfile, error) {
+	profiles := make(map[string]*userProfile)
+	ruleSets := make(map[string]*rules.RuleSet)
+
+	for _, u := range users {
+		if u.Place == "" && u.WorkDays == "" && u.Rules == "" {
+			continue
+		}
+
+		profile := &userProfile{place: u.Place}
+
+		if u.WorkDays != "" {
+			workDays, err := parseWorkDays(u.WorkDays)
+			if err != nil {
+				return nil, fmt.Errorf("ユーザーID %s の work_days が不正です: %v", u.UserID, err)
+			}
+			profile.workDays = workDays
+		}
+
+		if u.Rules != "" {
+			path := resolveConfigPath(configDir, u.Rules)
+			if ruleSets[path] == nil {
+				rs, err := rules.Load(path)
+				if err != nil {
+					return nil, fmt.Errorf("ユーザーID %s のルールの読み込みに失敗しました: %v", u.UserID, err)
+				}
+				ruleSets[path] = rs
+			}
+			profile.rules = ruleSets[path]
+		}
+
+		profiles[u.UserID] = profile
+	}
+	return profiles, nil
+}
+
+// weekdayAliases は work_days に指定できる曜日の表記です
+var weekdayAliases = map[string]time.Weekday{
+	"日": time.Sunday, "sun": time.Sunday,
+	"月": time.Monday, "mon": time.Monday,
+	"火": time.Tuesday, "tue": time.Tuesday,
+	"水": time.Wednesday, "wed": time.Wednesday,
+	"木": time.Thursday, "thu": time.Thursday,
+	"金": time.Friday, "fri": time.Friday,
+	"土": time.Saturday, "sat": time.Saturday,
+}
+
+// parseWorkDays は勤務する曜日の指定を解析します
+// 曜日はカンマまたは空白で区切り（"mon,wed,fri"）、漢字の曜日は続けて書くこともできます（"月水金"）
+// "月-金"、"mon-fri" のように範囲も指定できます
+func parseWorkDays(s string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || r == '、' || r == ' ' || r == '　'
+	})
+	for _, field := range fields {
+		if from, to, ok := strings.Cut(field, "-"); ok {
+			start, ok1 := weekdayAliases[from]
+			end, ok2 := weekdayAliases[to]
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("invalid weekday range %q", field)
+			}
+			for d := start; ; d = (d + 1) % 7 {
+				days[d] = true
+				if d == end {
+					break
+				}
+			}
+			continue
+		}
+
+		if d, ok := weekdayAliases[field]; ok {
+			days[d] = true
+			continue
+		}
+
+		// 漢字の曜日を続けて書いた場合（"月水金"）
+		for _, r := range field {
+			d, ok := weekdayAliases[string(r)]
+			if !ok {
+				return nil, fmt.Errorf("unknown weekday %q", field)
+			}
+			days[d] = true
+		}
+	}
+
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no weekdays in %q", s)
+	}
+	return days, nil
+}
+
+// loadOffDayLabel は勤務しない曜日に書き込むラベルを環境変数 OFF_DAY_LABEL から読み込みます
+func loadOffDayLabel() string {
+	if label := os.Getenv("OFF_DAY_LABEL"); label != "" {
+		return label
+	}
+	return defaultOffDayLabel
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWorkDays(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []time.Weekday
+		expectError bool
+	}{
+		{"kanji", "月水金", []time.Weekday{time.Monday, time.Wednesday, time.Friday}, false},
+		{"kanji with separators", "月、水, 金", []time.Weekday{time.Monday, time.Wednesday, time.Friday}, false},
+		{"english", "Mon,Thu", []time.Weekday{time.Monday, time.Thursday}, false},
+		{"range", "月-金", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, false},
+		{"range across sunday", "fri-mon", []time.Weekday{time.Sunday, time.Monday, time.Friday, time.Saturday}, false},
+		{"unknown weekday", "月曜", nil, true},
+		{"invalid range", "mon-xyz", nil, true},
+		{"empty", " , ", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, err := parseWorkDays(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expected := make(map[time.Weekday]bool)
+			for _, d := range tt.expected {
+				expected[d] = true
+			}
+			if !reflect.DeepEqual(days, expected) {
+				t.Errorf("expected %v but got %v", expected, days)
+			}
+		})
+	}
+}
+
+func TestLoadUserProfiles(t *testing.T) {
+	dir := t.TempDir()
+	rulesJSON := `{"default": "在宅", "rules": [{"name": "出社", "subject": "出社", "label": "本社"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "remote.json"), []byte(rulesJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := loadUserProfiles(dir, []mapping.UserMapping{
+		{UserID: "1", HeaderName: "伊藤", Place: "大阪", WorkDays: "月水金"},
+		{UserID: "2", HeaderName: "三浦"},
+		{UserID: "3", HeaderName: "佐藤", Rules: "remote.json"},
+		{UserID: "4", HeaderName: "田中", Rules: filepath.Join(dir, "remote.json")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := profiles["2"]; ok {
+		t.Errorf("expected no profile for user without settings")
+	}
+	if p := profiles["1"]; p == nil || p.place != "大阪" || !p.worksOn(time.Monday) || p.worksOn(time.Tuesday) {
+		t.Errorf("unexpected profile for user 1: %+v", p)
+	}
+	if p := profiles["3"]; p == nil || p.rules == nil || p.rules.Default != "在宅" {
+		t.Fatalf("unexpected profile for user 3: %+v", p)
+	}
+	if profiles["3"].rules != profiles["4"].rules {
+		t.Errorf("expected the same rules file to be loaded once")
+	}
+
+	if _, err := loadUserProfiles(dir, []mapping.UserMapping{{UserID: "1", WorkDays: "毎日"}}); err == nil {
+		t.Errorf("expected error for invalid work_days but got none")
+	}
+	if _, err := loadUserProfiles(dir, []mapping.UserMapping{{UserID: "1", Rules: "missing.json"}}); err == nil {
+		t.Errorf("expected error for missing rules file but got none")
+	}
+}
+
+func TestScheduleWriterUserProfile(t *testing.T) {
+	writer, fake := newFebruaryWriter(t, WriteOptions{
+		PastDates: PastDateAll,
+		Today:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.Local),
+	})
+	workDays, err := parseWorkDays("月水金")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.profiles = map[string]*userProfile{"1": {place: "大阪", workDays: workDays}}
+
+	if err := writeFebruary(writer, fake, februaryEvents()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"R6年度_2月!D8":  "週休",     // 2/1（土）は勤務しない曜日
+		"R6年度_2月!D10": "大阪",     // 2/3（月）は勤務地
+		"R6年度_2月!D11": "週休",     // 2/4（火）は勤務しない曜日
+		"R6年度_2月!D17": "週休",     // 2/10（月）は休暇
+		"R6年度_2月!D19": "外出",     // 2/12（水）は外出
+		"R6年度_2月!D21": "午前休/大阪", // 2/14（金）は午前休
+	}
+	for cell, want := range expected {
+		if got := fake.get("book", cell); got != want {
+			t.Errorf("%s: expected %q but got %q", cell, want, got)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"github.com/eotel/garoon2gs/organizations"
+	"github.com/eotel/garoon2gs/users"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ヘッダー名に使用するユーザーの項目（USER_HEADER_FIELD）
+const (
+	headerFieldName    = "name"    // Garoonの表示名
+	headerFieldCode    = "code"    // ログイン名
+	headerFieldSurname = "surname" // 表示名の最初の空白より前（"伊藤 太郎" → "伊藤"）
+)
+
+// userSource は実行のたびにGaroonの組織からユーザーを求めるための設定です
+type userSource struct {
+	orgs        []string // 組織IDまたは組織コード（USER_SOURCE_ORGS）
+	recursive   bool     // 子組織のメンバーも含める（USER_SOURCE_RECURSIVE）
+	headerField string   // ヘッダー名に使用する項目（USER_HEADER_FIELD）
+}
+
+// loadUserSource は環境変数からユーザーの取得元の組織の設定を読み込みます
+// USER_SOURCE_ORGS が未指定の場合はnilを返します
+func loadUserSource() (*userSource, error) {
+	orgs := splitList(os.Getenv("USER_SOURCE_ORGS"))
+	if len(orgs) == 0 {
+		return nil, nil
+	}
+
+	source := &userSource{orgs: orgs, headerField: headerFieldName}
+
+	if v := os.Getenv("USER_SOURCE_RECURSIVE"); v != "" {
+		recursive, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("USER_SOURCE_RECURSIVEの値が不正です: %q", v)
+		}
+		source.recursive = recursive
+	}
+
+	if v := os.Getenv("USER_HEADER_FIELD"); v != "" {
+		switch v {
+		case headerFieldName, headerFieldCode, headerFieldSurname:
+			source.headerField = v
+		default:
+			return nil, fmt.Errorf("USER_HEADER_FIELDの値が不正です: %q（name, code, surname のいずれかを指定してください）", v)
+		}
+	}
+	return source, nil
+}
+
+// Resolve は組織のメンバーを取得し、ユーザーマッピングを作成します
+// overrides（USER_MAPPING_PATH のCSV）に記載されたユーザーは、CSVのヘッダー名とチームなどの設定を使用します
+// ヘッダー名が重複するユーザーは、誤った列に書き込まないよう対象から除きます
+func (s *userSource) Resolve(ctx context.Context, client *http.Client, baseURL, username, password string, overrides []mapping.UserMapping) ([]mapping.UserMapping, error) {
+	orgs, err := organizations.ListOrganizations(ctx, client, baseURL, username, password)
+	if err != nil {
+		return nil, fmt.Errorf("組織一覧の取得に失敗しました: %v", err)
+	}
+
+	orgIDs, err := organizations.ResolveOrganizationIDs(orgs, s.orgs)
+	if err != nil {
+		return nil, err
+	}
+	if s.recursive {
+		orgIDs = organizations.ExpandOrganizationIDs(orgs, orgIDs)
+	}
+
+	members, err := organizations.GetMembers(ctx, client, baseURL, username, password, orgIDs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	overrideByID := make(map[string]mapping.UserMapping, len(overrides))
+	for _, m := range overrides {
+		overrideByID[m.UserID] = m
+	}
+
+	mappings := make([]mapping.UserMapping, 0, len(members))
+	for _, u := range members {
+		if m, ok := overrideByID[u.ID]; ok {
+			mappings = append(mappings, m)
+			continue
+		}
+		mappings = append(mappings, mapping.UserMapping{UserID: u.ID, Code: u.Code, Email: u.Email, HeaderName: s.headerName(u)})
+	}
+
+	mappings = excludeDuplicateHeaders(mappings)
+	log.Printf("%d個の組織から%d人のユーザーを取得しました", len(orgIDs), len(mappings))
+	return mappings, nil
+}
+
+// headerName はユーザーのヘッダー名を求めます
+func (s *userSource) headerName(u users.User) string {
+	switch s.headerField {
+	case headerFieldCode:
+		return u.Code
+	case headerFieldSurname:
+		return surnameOf(u.Name)
+	default:
+		return strings.TrimSpace(u.Name)
+	}
+}
+
+// surnameOf は表示名の最初の空白（全角を含む）より前を姓として返します
+func surnameOf(name string) string {
+	fields := strings.Fields(strings.ReplaceAll(name, "　", " "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// excludeDuplicateHeaders はヘッダー名が空または重複するユーザーを除きます
+func excludeDuplicateHeaders(mappings []mapping.UserMapping) []mapping.UserMapping {
+	count := make(map[string]int, len(mappings))
+	for _, m := range mappings {
+		count[m.HeaderName]++
+	}
+
+	filtered := make([]mapping.UserMapping, 0, len(mappings))
+	for _, m := range mappings {
+		switch {
+		case m.HeaderName == "":
+			log.Printf("警告: ユーザーID %s のヘッダー名が空のため対象から除きます", m.UserID)
+		case count[m.HeaderName] > 1:
+			log.Printf("警告: ユーザーID %s のヘッダー名 %q が他のユーザーと重複するため対象から除きます（USER_MAPPING_PATHでヘッダー名を指定してください）", m.UserID, m.HeaderName)
+		default:
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"github.com/eotel/garoon2gs/internal/fakegaroon"
+	"github.com/eotel/garoon2gs/internal/mapping"
+	"reflect"
+	"testing"
+)
+
+func TestUserSourceResolve(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddUsers(
+		fakegaroon.User{ID: "1", Code: "ito", Name: "伊藤 太郎"},
+		fakegaroon.User{ID: "2", Code: "miura", Name: "三浦　花子"},
+		fakegaroon.User{ID: "3", Code: "sato", Name: "佐藤 一郎"},
+		fakegaroon.User{ID: "4", Code: "sato2", Name: "佐藤 次郎"},
+	)
+	srv.AddOrganizations(
+		fakegaroon.Organization{ID: "10", Name: "開発部", Code: "dev", Members: []string{"1", "2"}},
+		fakegaroon.Organization{ID: "11", Name: "開発1課", Code: "dev1", ParentID: "10", Members: []string{"2", "3", "4"}},
+	)
+
+	tests := []struct {
+		name      string
+		source    userSource
+		overrides []mapping.UserMapping
+		expected  []mapping.UserMapping
+	}{
+		{
+			name:   "表示名",
+			source: userSource{orgs: []string{"dev"}, headerField: headerFieldName},
+			expected: []mapping.UserMapping{
+				{UserID: "1", Code: "ito", HeaderName: "伊藤 太郎"},
+				{UserID: "2", Code: "miura", HeaderName: "三浦　花子"},
+			},
+		},
+		{
+			name:   "ログイン名と子組織",
+			source: userSource{orgs: []string{"10"}, recursive: true, headerField: headerFieldCode},
+			expected: []mapping.UserMapping{
+				{UserID: "1", Code: "ito", HeaderName: "ito"},
+				{UserID: "2", Code: "miura", HeaderName: "miura"},
+				{UserID: "3", Code: "sato", HeaderName: "sato"},
+				{UserID: "4", Code: "sato2", HeaderName: "sato2"},
+			},
+		},
+		{
+			name:   "姓（重複する姓は除く）",
+			source: userSource{orgs: []string{"dev1"}, headerField: headerFieldSurname},
+			expected: []mapping.UserMapping{
+				{UserID: "2", Code: "miura", HeaderName: "三浦"},
+			},
+		},
+		{
+			name:   "上書き",
+			source: userSource{orgs: []string{"dev1"}, headerField: headerFieldSurname},
+			overrides: []mapping.UserMapping{
+				{UserID: "3", HeaderName: "佐藤一", Team: "開発"},
+				{UserID: "9", HeaderName: "退職者"},
+			},
+			expected: []mapping.UserMapping{
+				{UserID: "2", Code: "miura", HeaderName: "三浦"},
+				{UserID: "3", HeaderName: "佐藤一", Team: "開発"},
+				{UserID: "4", Code: "sato2", HeaderName: "佐藤"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.Resolve(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, tt.overrides)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	source := userSource{orgs: []string{"sales"}, headerField: headerFieldName}
+	if _, err := source.Resolve(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, nil); err == nil {
+		t.Error("expected error for unknown organization but got none")
+	}
+}
+
+func TestLoadUserSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    *userSource
+		expectError bool
+	}{
+		{name: "未指定", env: map[string]string{}},
+		{
+			name:     "デフォルト",
+			env:      map[string]string{"USER_SOURCE_ORGS": "10, dev"},
+			expected: &userSource{orgs: []string{"10", "dev"}, headerField: headerFieldName},
+		},
+		{
+			name:     "子組織と姓",
+			env:      map[string]string{"USER_SOURCE_ORGS": "10", "USER_SOURCE_RECURSIVE": "true", "USER_HEADER_FIELD": "surname"},
+			expected: &userSource{orgs: []string{"10"}, recursive: true, headerField: headerFieldSurname},
+		},
+		{name: "不正な項目", env: map[string]string{"USER_SOURCE_ORGS": "10", "USER_HEADER_FIELD": "email"}, expectError: true},
+		{name: "不正な真偽値", env: map[string]string{"USER_SOURCE_ORGS": "10", "USER_SOURCE_RECURSIVE": "yes!"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"USER_SOURCE_ORGS", "USER_SOURCE_RECURSIVE", "USER_HEADER_FIELD"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got, err := loadUserSource()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v but got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,11 +1,12 @@
 package users
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"github.com/eotel/garoon2gs/internal/pagination"
 	"net/http"
+	"net/url"
 )
 
 // User represents a Garoon user
@@ -21,42 +22,43 @@ type User struct {
 	} `json:"primaryOrganization"`
 }
 
-// ListUsers はユーザー一覧を取得する関数です
-func ListUsers(client *http.Client, baseURL, username, password string) ([]User, error) {
-	reqURL := fmt.Sprintf("%s/api/v1/base/users", baseURL)
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("リクエストの作成に失敗しました: %v", err)
-	}
+// Filter はユーザー一覧の絞り込み条件です（空の項目では絞り込みません）
+type Filter struct {
+	Name string // 名前での検索（Garoonの name パラメーター。表示名やログイン名の一部で検索します）
+	Code string // ログイン名（完全一致）
+}
 
-	// Basic認証ヘッダーの設定
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
-	req.Header.Set("X-Cybozu-Authorization", auth)
+// ListUsers はユーザー一覧を取得する関数です
+// 一覧はページごとに取得し、すべてのユーザーを返します
+func ListUsers(ctx context.Context, client *http.Client, baseURL, username, password string) ([]User, error) {
+	return SearchUsers(ctx, client, baseURL, username, password, Filter{})
+}
 
-	// リクエストの実行
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("APIリクエストに失敗しました: %v", err)
+// SearchUsers は filter に一致するユーザーの一覧を取得する関数です
+func SearchUsers(ctx context.Context, client *http.Client, baseURL, username, password string, filter Filter) ([]User, error) {
+	// ログイン名のみの指定でも取得件数を減らせるよう、名前での検索に使用する
+	params := url.Values{}
+	if filter.Name != "" {
+		params.Set("name", filter.Name)
+	} else if filter.Code != "" {
+		params.Set("name", filter.Code)
 	}
-	defer resp.Body.Close()
 
-	// レスポンスのステータスコードチェック
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("APIエラー（ステータスコード: %d）: %s", resp.StatusCode, string(body))
+	all, err := pagination.FetchAll[User](ctx, client, baseURL, "/api/v1/base/users", username, password, params, "users")
+	if err != nil {
+		return nil, err
 	}
 
-	// レスポンスの読み取りとJSON解析
-	var response struct {
-		Users []User `json:"users"`
+	if filter.Code == "" {
+		return all, nil
 	}
-
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&response); err != nil {
-		return nil, fmt.Errorf("JSONのデコードに失敗しました: %v", err)
+	var matched []User
+	for _, u := range all {
+		if u.Code == filter.Code {
+			matched = append(matched, u)
+		}
 	}
-
-	return response.Users, nil
+	return matched, nil
 }
 
 // PrintUsers はユーザー一覧を整形して出力する関数です
@@ -0,0 +1,151 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"github.com/eotel/garoon2gs/internal/fakegaroon"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListUsers(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddUsers(
+		fakegaroon.User{ID: "1", Code: "ito", Name: "伊藤", Email: "ito@example.com"},
+		fakegaroon.User{ID: "2", Code: "miura", Name: "三浦"},
+	)
+
+	users, err := ListUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users but got %d", len(users))
+	}
+	if users[0].ID != "1" || users[0].Code != "ito" || users[0].Name != "伊藤" || users[0].Email != "ito@example.com" {
+		t.Errorf("unexpected user: %+v", users[0])
+	}
+}
+
+func TestListUsersPaging(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	for i := 1; i <= 250; i++ {
+		srv.AddUsers(fakegaroon.User{ID: strconv.Itoa(i), Code: fmt.Sprintf("user%d", i), Name: fmt.Sprintf("ユーザー%d", i)})
+	}
+
+	users, err := ListUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(users) != 250 {
+		t.Fatalf("expected 250 users but got %d", len(users))
+	}
+	if users[0].ID != "1" || users[249].ID != "250" {
+		t.Errorf("unexpected order: first %s, last %s", users[0].ID, users[249].ID)
+	}
+	if n := srv.Requests("/api/v1/base/users"); n != 3 {
+		t.Errorf("expected 3 requests but got %d", n)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddUsers(
+		fakegaroon.User{ID: "1", Code: "ito", Name: "伊藤 太郎"},
+		fakegaroon.User{ID: "2", Code: "ito2", Name: "伊藤 花子"},
+		fakegaroon.User{ID: "3", Code: "miura", Name: "三浦"},
+	)
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"no filter", Filter{}, []string{"1", "2", "3"}},
+		{"name", Filter{Name: "伊藤"}, []string{"1", "2"}},
+		{"code", Filter{Code: "ito"}, []string{"1"}},
+		{"name and code", Filter{Name: "花子", Code: "ito"}, nil},
+		{"no match", Filter{Code: "sato"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := SearchUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password, tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, u := range users {
+				ids = append(ids, u.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("expected %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestListUsersErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		fault       *fakegaroon.Fault
+		expectError string
+	}{
+		{
+			name:        "パスワード誤り",
+			password:    "wrong",
+			expectError: "ステータスコード: 401",
+		},
+		{
+			name:        "証明書なし",
+			password:    fakegaroon.Password,
+			fault:       &fakegaroon.Fault{Status: 496},
+			expectError: "ステータスコード: 496",
+		},
+		{
+			name:        "サーバーエラー",
+			password:    fakegaroon.Password,
+			fault:       &fakegaroon.Fault{Status: http.StatusBadGateway},
+			expectError: "ステータスコード: 502",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakegaroon.NewServer()
+			defer srv.Close()
+			if tt.fault != nil {
+				srv.AddFault(*tt.fault)
+			}
+
+			_, err := ListUsers(context.Background(), srv.Client(), srv.URL, fakegaroon.Username, tt.password)
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing %q but got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestListUsersCancelled(t *testing.T) {
+	srv := fakegaroon.NewServer()
+	defer srv.Close()
+	srv.AddFault(fakegaroon.Fault{Delay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := ListUsers(ctx, srv.Client(), srv.URL, fakegaroon.Username, fakegaroon.Password); err == nil {
+		t.Error("expected error for slow response but got none")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WriteState はgaroon2gsが最後に書き込んだセルの値を記録する構造体です
+// 現在の値が記録と異なるセルは手動で編集されたものとみなし、上書きしません
+type WriteState struct {
+	path  string
+	mu    sync.Mutex
+	cells map[string]map[string]string // スプレッドシートID → セル範囲 → 最後に書き込んだ値
+}
+
+// LoadWriteState は状態ファイルを読み込みます
+// ファイルが存在しない場合は空の状態を返します
+func LoadWriteState(path string) (*WriteState, error) {
+	state := &WriteState{
+		path:  path,
+		cells: make(map[string]map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %v", path, err)
+	}
+
+	var file struct {
+		Cells map[string]map[string]string `json:"cells"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+	}
+	if file.Cells != nil {
+		state.cells = file.Cells
+	}
+	return state, nil
+}
+
+// LastWritten は指定されたセルに最後に書き込んだ値を返します
+func (s *WriteState) LastWritten(spreadsheetID, cellRange string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.cells[spreadsheetID][cellRange]
+	return value, ok
+}
+
+// Record はセルに書き込んだ値を記録します
+func (s *WriteState) Record(spreadsheetID, cellRange, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cells[spreadsheetID] == nil {
+		s.cells[spreadsheetID] = make(map[string]string)
+	}
+	s.cells[spreadsheetID][cellRange] = value
+}
+
+// IsManualEdit は現在の値が最後に書き込んだ値と異なる（手動で編集された）かどうかを判定します
+// 空のセルと、書き込んだ記録のないセルは手動編集とみなしません
+func (s *WriteState) IsManualEdit(spreadsheetID, cellRange, current string) bool {
+	if current == "" {
+		return false
+	}
+	last, ok := s.LastWritten(spreadsheetID, cellRange)
+	return ok && last != current
+}
+
+// Save は状態ファイルを書き込みます
+func (s *WriteState) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(struct {
+		Cells map[string]map[string]string `json:"cells"`
+	}{s.cells}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %v", err)
+	}
+
+	// 書き込み途中で中断されても壊れないよう、一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".garoon2gs_state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file %s: %v", s.path, err)
+	}
+	return nil
+}
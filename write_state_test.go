@@ -0,0 +1,49 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	// 存在しないファイルは空の状態として読み込まれる
+	state, err := LoadWriteState(path)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if _, ok := state.LastWritten("sheet-id", "R7年度_5月!J8"); ok {
+		t.Fatal("expected no record in empty state")
+	}
+
+	state.Record("sheet-id", "R7年度_5月!J8", "渋谷")
+	if err := state.Save(); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+
+	reloaded, err := LoadWriteState(path)
+	if err != nil {
+		t.Fatalf("failed to reload state: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		rng      string
+		current  string
+		expected bool
+	}{
+		{"前回と同じ値", "R7年度_5月!J8", "渋谷", false},
+		{"前回と異なる値", "R7年度_5月!J8", "在宅", true},
+		{"空のセル", "R7年度_5月!J8", "", false},
+		{"記録のないセル", "R7年度_5月!J9", "在宅", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reloaded.IsManualEdit("sheet-id", tt.rng, tt.current); got != tt.expected {
+				t.Errorf("expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}